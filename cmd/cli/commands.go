@@ -0,0 +1,752 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/agukrapo/playlist-creator/deezer"
+	"github.com/agukrapo/playlist-creator/internal/cache"
+	"github.com/agukrapo/playlist-creator/internal/env"
+	"github.com/agukrapo/playlist-creator/internal/journal"
+	"github.com/agukrapo/playlist-creator/internal/random"
+	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/agukrapo/playlist-creator/internal/retry"
+	"github.com/agukrapo/playlist-creator/playlists"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(a *app, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"create", "search the tracks of a file and push them to a playlist", (*app).create},
+	{"resolve", "search the tracks of a file and write the chosen ones to a lock file", (*app).resolve},
+	{"push", "push the tracks of a lock file to a playlist, without searching", (*app).push},
+	{"search", "search a track and print its ranked matches", (*app).search},
+	{"list-playlists", "print the ID and name of the user playlists", (*app).listPlaylists},
+	{"transfer", "copy a playlist from a target to another", (*app).transfer},
+	{"login", "log in to a target and store its credentials", (*app).login},
+}
+
+func (a *app) dispatch(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		usage()
+		return errors.New("command missing")
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return flag.ErrHelp
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(a, ctx, args[1:])
+		}
+	}
+
+	return a.legacy(ctx, args)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+
+	_, _ = fmt.Fprintf(out, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", appName)
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(out, "  %-15s %s\n", c.name, c.summary)
+	}
+
+	_, _ = fmt.Fprintf(out, "\nRun '%s <command> --help' for the command flags.\n", appName)
+	_, _ = fmt.Fprintf(out, "\nAlias of create: %s [flags] <target> <file> [create|append|replace] [playlist]\n", appName)
+}
+
+// newFlagSet creates the flag set of a command, args describes its positional arguments.
+func newFlagSet(name, args, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage: %s [flags] %s\n\n%s\n\nFlags:\n", strings.TrimSpace(appName+" "+name), args, summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+func targetFlag(fs *flag.FlagSet, name string) *string {
+	return fs.String(name, "", "target `name`: spotify, deezer or mock")
+}
+
+func (a *app) promptFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&a.yes, "yes", false, "never prompt, skipping confirmations and failing instead of logging in")
+	fs.BoolVar(&a.yes, "y", false, "shorthand for -yes")
+	fs.BoolVar(&a.dryRun, "dry-run", false, "print the resolved tracks and stop")
+}
+
+func (a *app) searchFlags(fs *flag.FlagSet) {
+	interactive, _ := env.Lookup[bool]("INTERACTIVE")
+	fs.BoolVar(&a.interactive, "interactive", interactive, "choose between the matches of every track, defaults to the INTERACTIVE environment variable")
+	fs.IntVar(&a.concurrency, "concurrency", 100, "maximum concurrent searches")
+	a.depthFlags(fs)
+	a.cacheFlags(fs)
+}
+
+func (a *app) depthFlags(fs *flag.FlagSet) {
+	market, _ := env.Lookup[string]("SPOTIFY_MARKET")
+	fs.IntVar(&a.depth, "depth", 0, "maximum search results per track, 50 on Spotify and 100 on Deezer by default")
+	fs.StringVar(&a.market, "market", market, "Spotify `market`, a country code or from_token, to only find tracks playable there, defaults to the SPOTIFY_MARKET environment variable")
+	fs.Func("unavailable", "`policy` for the matches that can't be played: last ranks them after the rest, drop leaves them out, keep ranks them as usual, defaults to last", func(v string) (err error) {
+		a.unavailable, err = playlists.ParseAvailability(v)
+		return err
+	})
+}
+
+func (a *app) cacheFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&a.noCache, "no-cache", false, "search without reading nor writing the search cache")
+	fs.BoolVar(&a.clearCache, "clear-cache", false, "empty the search cache before searching")
+	fs.DurationVar(&a.cacheTTL, "cache-ttl", cache.DefaultTTL, "how long the search results are cached")
+}
+
+func (a *app) formatFlag(fs *flag.FlagSet) {
+	format, _ := env.Lookup[string]("INPUT_FORMAT")
+	fs.StringVar(&a.format, "format", format, "input `format`: text, csv, json, m3u, m3u8, pls or lock, defaults to the file extension")
+}
+
+func (a *app) playlistFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&a.public, "public", false, "make the new playlist public")
+	fs.BoolVar(&a.collaborative, "collaborative", false, "make the new playlist collaborative, it can't be public")
+	fs.StringVar(&a.description, "description", "", "new playlist description")
+	fs.StringVar(&a.cover, "cover", "", "new playlist cover image `path`, a JPEG up to 190 KB")
+	fs.BoolVar(&a.resume, "resume", false, "continue the last failed push of the same source to the same target, without searching")
+}
+
+func modeFlag(fs *flag.FlagSet) *string {
+	return fs.String("mode", "create", "create a new playlist, or append the missing tracks to an existing one, or replace its tracks")
+}
+
+func nameFlag(fs *flag.FlagSet) *string {
+	return fs.String("name", "", "playlist name, or existing playlist name or ID with append and replace modes, defaults to the file name")
+}
+
+// parse parses the flags and checks the number of positional arguments.
+func parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if n := fs.NArg(); n < minArgs || (maxArgs >= 0 && n > maxArgs) {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	return nil
+}
+
+func (a *app) create(ctx context.Context, args []string) error {
+	fs := newFlagSet("create", "<file>", "Searches the tracks of the file and pushes them to a playlist.")
+	target := targetFlag(fs, "target")
+	mode := modeFlag(fs)
+	name := nameFlag(fs)
+	a.playlistFlags(fs)
+	a.promptFlags(fs)
+	a.searchFlags(fs)
+	a.formatFlag(fs)
+
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	return a.createPlaylist(ctx, *target, fs.Arg(0), *mode, *name)
+}
+
+// legacy runs create with positional arguments: [flags] <target> <file> [create|append|replace] [playlist].
+func (a *app) legacy(ctx context.Context, args []string) error {
+	fs := newFlagSet("", "<target> <file> [create|append|replace] [playlist]", "Alias of create.")
+	a.playlistFlags(fs)
+	a.promptFlags(fs)
+	a.searchFlags(fs)
+	a.formatFlag(fs)
+
+	if err := parse(fs, args, 2, 4); err != nil {
+		return err
+	}
+
+	mode := "create"
+	if fs.NArg() > 2 {
+		mode = fs.Arg(2)
+	}
+
+	return a.createPlaylist(ctx, fs.Arg(0), fs.Arg(1), mode, fs.Arg(3))
+}
+
+func (a *app) createPlaylist(ctx context.Context, targetName, file, mode, name string) error {
+	if err := checkMode(mode); err != nil {
+		return err
+	}
+
+	opts, err := a.playlistOptions()
+	if err != nil {
+		return err
+	}
+
+	target, err := a.buildTarget(ctx, targetName)
+	if err != nil {
+		return err
+	}
+
+	j, err := fileJournal(target, file)
+	if err != nil {
+		return err
+	}
+
+	if a.resume {
+		return a.resumePush(ctx, target, mode, j)
+	}
+
+	lines, fileName, err := a.openFile(file)
+	if err != nil {
+		return err
+	}
+
+	name = a.playlistName(mode, name, fileName)
+
+	manager := playlists.NewManager(target, a.concurrency)
+
+	data, err := a.gather(ctx, manager, lines)
+	if err != nil {
+		return err
+	}
+
+	return a.save(ctx, manager, data, mode, name, opts, j)
+}
+
+func (a *app) resolve(ctx context.Context, args []string) error {
+	fs := newFlagSet("resolve", "<file>", "Searches the tracks of the file and writes the chosen ones to a lock file.")
+	target := targetFlag(fs, "target")
+	output := fs.String("output", "", "lock file `path`, defaults to the file name with .lock extension")
+	a.promptFlags(fs)
+	a.searchFlags(fs)
+	a.formatFlag(fs)
+
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	t, err := a.buildTarget(ctx, *target)
+	if err != nil {
+		return err
+	}
+
+	lines, name, err := a.openFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Join(filepath.Dir(fs.Arg(0)), name+".lock")
+	}
+
+	data, err := a.gather(ctx, playlists.NewManager(t, a.concurrency), lines)
+	if err != nil {
+		return err
+	}
+
+	songs, unresolved := data.Slice()
+
+	if a.dryRun {
+		printTracks(data)
+		return notFound(len(unresolved))
+	}
+
+	if err := writeLock(path, data.Lock()); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d tracks resolved, %d unresolved, written to %s\n", len(songs), len(unresolved), path)
+
+	return notFound(len(unresolved))
+}
+
+func (a *app) push(ctx context.Context, args []string) error {
+	fs := newFlagSet("push", "<lockfile>", "Pushes the tracks of a lock file written by resolve to a playlist, without searching.")
+	target := fs.String("target", "", "target `name`: spotify, deezer or mock, defaults to the one the lock file was resolved for")
+	mode := modeFlag(fs)
+	name := nameFlag(fs)
+	a.playlistFlags(fs)
+	a.promptFlags(fs)
+
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	if err := checkMode(*mode); err != nil {
+		return err
+	}
+
+	opts, err := a.playlistOptions()
+	if err != nil {
+		return err
+	}
+
+	a.format = "lock"
+	lines, fileName, err := a.openFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *target == "" {
+		if *target, err = lockTarget(lines); err != nil {
+			return err
+		}
+	}
+
+	t, err := a.buildTarget(ctx, *target)
+	if err != nil {
+		return err
+	}
+
+	j, err := fileJournal(t, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if a.resume {
+		return a.resumePush(ctx, t, *mode, j)
+	}
+
+	manager := playlists.NewManager(t, 1)
+
+	data := results.New(len(lines))
+	for i, item := range lines {
+		switch {
+		case item.Active() && !manager.Trusted(item) && item.Target() == "":
+			return fmt.Errorf("%q: %s is not a %s track, run resolve first", item.Query(), item.ID(), t.Name())
+		case item.Active() && !manager.Trusted(item):
+			return fmt.Errorf("%q: locked for %s, run resolve first", item.Query(), item.Target())
+		case item.Active():
+			if ok, _ := data.Put(i, item); !ok {
+				warn(fmt.Sprintf("Duplicated  for %q: name %q", item.Query(), item.Name()))
+			}
+		case item.Unresolved():
+			warn(fmt.Sprintf("%q: unresolved, skipped", item.Query()))
+			data.Put(i, item)
+		default:
+			return fmt.Errorf("%q: not resolved, run resolve first", item.Query())
+		}
+	}
+
+	if data.Empty() {
+		return errors.New("no resolved tracks")
+	}
+
+	if err := t.Setup(ctx); err != nil {
+		return fmt.Errorf("%s: setup: %w", t.Name(), err)
+	}
+
+	return a.save(ctx, manager, data, *mode, a.playlistName(*mode, *name, fileName), opts, j)
+}
+
+// lockTarget returns the target the locked items were resolved for.
+func lockTarget(lines []results.Item) (string, error) {
+	var out string
+	for _, item := range lines {
+		switch target := item.Target(); {
+		case target == "" || target == out:
+		case out == "":
+			out = target
+		default:
+			return "", fmt.Errorf("the lock file mixes %s and %s tracks, set -target", out, target)
+		}
+	}
+
+	if out == "" {
+		return "", errors.New("the lock file names no target, set -target")
+	}
+
+	return out, nil
+}
+
+func (a *app) search(ctx context.Context, args []string) error {
+	fs := newFlagSet("search", "<query>...", "Searches a track, written as a file line, and prints its ranked matches.")
+	target := targetFlag(fs, "target")
+	a.depthFlags(fs)
+	a.cacheFlags(fs)
+
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+
+	t, err := a.buildTarget(ctx, *target)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Setup(ctx); err != nil {
+		return fmt.Errorf("%s: setup: %w", t.Name(), err)
+	}
+
+	item := results.ParseItem(strings.Join(fs.Args(), " "))
+
+	manager := playlists.NewManager(t, 1)
+	manager.SetUnavailable(a.unavailable)
+
+	matches, err := manager.Search(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("%q: %w", item.Query(), playlists.ErrTrackNotFound)
+	}
+
+	for _, m := range matches {
+		fmt.Printf("%3.0f%%\t%s\t%s%s\n", m.Score*100, m.ID, m.Name, unavailable(m))
+	}
+
+	return nil
+}
+
+func (a *app) listPlaylists(ctx context.Context, args []string) error {
+	fs := newFlagSet("list-playlists", "", "Prints the ID and name of the user playlists.")
+	target := targetFlag(fs, "target")
+
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	t, err := a.buildTarget(ctx, *target)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Setup(ctx); err != nil {
+		return fmt.Errorf("%s: setup: %w", t.Name(), err)
+	}
+
+	all, err := t.Playlists(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: playlists: %w", t.Name(), err)
+	}
+
+	for _, p := range all {
+		fmt.Printf("%s\t%s\n", p.ID, p.Name)
+	}
+
+	return nil
+}
+
+func (a *app) transfer(ctx context.Context, args []string) error {
+	fs := newFlagSet("transfer", "<playlist>", "Copies a playlist, found by name or ID, from a target to another.")
+	from := targetFlag(fs, "from")
+	to := targetFlag(fs, "to")
+	name := fs.String("name", "", "new playlist name, defaults to the source one")
+	a.playlistFlags(fs)
+	a.promptFlags(fs)
+	a.searchFlags(fs)
+
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	opts, err := a.playlistOptions()
+	if err != nil {
+		return err
+	}
+
+	source, err := a.buildTarget(ctx, *from)
+	if err != nil {
+		return err
+	}
+
+	destination, err := a.buildTarget(ctx, *to)
+	if err != nil {
+		return err
+	}
+
+	j, err := journal.Default(destination.Name(), source.Name()+":"+fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if a.resume {
+		return a.resumePush(ctx, destination, "create", j)
+	}
+
+	playlist, lines, err := playlists.Export(ctx, source, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *name == "" {
+		*name = playlist.Name
+	}
+
+	manager := playlists.NewManager(destination, a.concurrency)
+
+	data, err := a.gather(ctx, manager, lines)
+	if err != nil {
+		return err
+	}
+
+	songs, _ := data.Slice()
+	fmt.Printf("\n%d out of %d %s tracks found on %s\n", len(songs), len(lines), source.Name(), destination.Name())
+
+	return a.save(ctx, manager, data, "create", *name, opts, j)
+}
+
+func (a *app) login(ctx context.Context, args []string) error {
+	fs := newFlagSet("login", "", "Logs in to the target and stores its credentials for the following runs.")
+	target := targetFlag(fs, "target")
+
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	switch *target {
+	case "spotify":
+		auth, err := a.spotifyAuth()
+		if err != nil {
+			return err
+		}
+
+		if err := spotifyLogin(ctx, auth); err != nil {
+			return err
+		}
+	case "deezer":
+		cookie, _ := env.Lookup[string]("DEEZER_ARL_COOKIE")
+		if cookie == "" {
+			fmt.Print("Deezer ARL cookie: ")
+
+			line, err := stdin.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			cookie = strings.TrimSpace(line)
+		}
+
+		if _, err := deezer.New(retry.Client("deezer"), cookie, a.log).Playlists(ctx); err != nil {
+			return &exitError{code: exitAuth, err: fmt.Errorf("deezer: %w", err)}
+		}
+
+		if err := a.creds.Set("deezer", "arl", cookie); err != nil {
+			return err
+		}
+	default:
+		_, err := a.buildTarget(ctx, *target)
+		return err
+	}
+
+	fmt.Println("Logged in")
+
+	return nil
+}
+
+func checkMode(mode string) error {
+	switch mode {
+	case "create", "append", "replace":
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %s", mode)
+	}
+}
+
+// playlistName defaults the name to the file name, with a random suffix on new playlists if APPEND_RANDOM_NAME is set.
+func (a *app) playlistName(mode, name, fileName string) string {
+	if name != "" {
+		return name
+	}
+
+	if v, _ := env.Lookup[bool]("APPEND_RANDOM_NAME"); v && mode == "create" {
+		return fileName + " " + random.Name(20)
+	}
+
+	return fileName
+}
+
+// save confirms and creates the named playlist with the given options, recording the progress in the journal,
+// or updates it in append and replace modes, with the active tracks of data.
+func (a *app) save(ctx context.Context, manager *playlists.Manager, data *results.Set, mode, name string, opts playlists.PlaylistOptions, j playlists.Journal) error {
+	songs, missing := data.Slice()
+
+	var msg string
+	switch mode {
+	case "append":
+		msg = fmt.Sprintf("Adding missing tracks out of %d to playlist %q", len(songs), name)
+	case "replace":
+		msg = fmt.Sprintf("Replacing playlist %q tracks with %d tracks", name, len(songs))
+	default:
+		msg = fmt.Sprintf("Creating playlist %q with %d tracks", name, len(songs))
+	}
+
+	if a.dryRun {
+		fmt.Printf("\nDry run: %s\n\n", msg)
+		printTracks(data)
+		return notFound(len(missing))
+	}
+
+	if !a.yes {
+		if err := confirm(msg); err != nil {
+			return err
+		}
+	}
+
+	switch mode {
+	case "append", "replace":
+		if err := manager.Update(ctx, name, songs, mode == "replace"); err != nil {
+			return &exitError{code: exitPush, err: err}
+		}
+		fmt.Println("Playlist updated")
+	default:
+		if p, ok, _ := j.Load(); ok {
+			warn(fmt.Sprintf("The previous push of playlist %q stopped after %d out of %d tracks, starting over", p.Name, p.Added, len(p.Tracks)))
+		}
+
+		if err := manager.Push(ctx, name, songs, opts, j); err != nil {
+			var cerr *playlists.CoverError
+			if errors.As(err, &cerr) {
+				warn(err)
+				fmt.Println("Playlist created")
+				return notFound(len(missing))
+			}
+
+			if _, ok, _ := j.Load(); ok {
+				err = fmt.Errorf("%w, run again with -resume to continue", err)
+			}
+			return &exitError{code: exitPush, err: err}
+		}
+		fmt.Println("Playlist created")
+	}
+
+	return notFound(len(missing))
+}
+
+// fileJournal returns the journal of the pushes of the file to the target.
+func fileJournal(target playlists.Target, file string) (*journal.File, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return journal.Default(target.Name(), path)
+}
+
+// resumePush confirms and continues the failed push recorded by the journal.
+func (a *app) resumePush(ctx context.Context, target playlists.Target, mode string, j playlists.Journal) error {
+	if mode != "create" {
+		return fmt.Errorf("nothing to resume in %s mode", mode)
+	}
+
+	p, ok, err := j.Load()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return playlists.ErrNothingToResume
+	}
+
+	msg := fmt.Sprintf("Resuming playlist %q with %d tracks, %d already added", p.Name, len(p.Tracks), p.Added)
+
+	if a.dryRun {
+		fmt.Printf("\nDry run: %s\n", msg)
+		return nil
+	}
+
+	if !a.yes {
+		if err := confirm(msg); err != nil {
+			return err
+		}
+	}
+
+	if err := playlists.NewManager(target, 1).Resume(ctx, j); err != nil {
+		return &exitError{code: exitPush, err: err}
+	}
+
+	fmt.Println("Playlist created")
+
+	return nil
+}
+
+// notFound reports the tracks left out, if any.
+func notFound(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	return &exitError{code: exitNotFound, err: fmt.Errorf("%d tracks not found, skipped or failed", n)}
+}
+
+func printTracks(data *results.Set) {
+	for _, item := range data.Lock() {
+		if item.Active() {
+			fmt.Printf("%s\t%s\n", item.Name(), item.Query())
+		}
+	}
+}
+
+func (a *app) gather(ctx context.Context, manager *playlists.Manager, lines []results.Item) (*results.Set, error) {
+	if a.concurrency < 1 {
+		return nil, fmt.Errorf("invalid concurrency %d", a.concurrency)
+	}
+
+	manager.SetUnavailable(a.unavailable)
+
+	data := results.New(len(lines))
+
+	var (
+		candidates []candidate
+		mu         sync.Mutex
+	)
+
+	if err := manager.Gather(ctx, lines, func(i int, item results.Item, res playlists.Result) {
+		switch {
+		case res.Status == playlists.Failed:
+			warn(fmt.Sprintf("%q: %v", item.Query(), res.Err))
+			data.Put(i, item)
+			return
+		case res.Status == playlists.NotFound:
+			warn(fmt.Sprintf("%q: %s", item.Query(), playlists.ErrTrackNotFound))
+			data.Put(i, item)
+			return
+		case len(res.Matches) == 0:
+			fmt.Printf("LOCKED %q: %s\n", item.Query(), item.Name())
+		default:
+			best := res.Matches[0]
+			fmt.Printf("%3.0f%% %q: %s\n", best.Score*100, item.Query(), best.Name)
+			if len(res.Matches) > 1 {
+				mu.Lock()
+				candidates = append(candidates, candidate{i: i, item: item, matches: res.Matches})
+				mu.Unlock()
+			}
+			item = item.WithID(best.ID).WithName(best.Name).WithActive(true)
+		}
+		if ok, _ := data.Put(i, item); !ok {
+			warn(fmt.Sprintf("Duplicated  for %q: name %q", item.Query(), item.Name()))
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	if a.interactive && !a.yes && len(candidates) != 0 {
+		slices.SortFunc(candidates, func(a, b candidate) int {
+			return cmp.Compare(a.i, b.i)
+		})
+
+		p := &picker{manager: manager, in: stdin, out: os.Stdout}
+		if err := p.pick(ctx, data, len(lines), candidates); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
@@ -4,29 +4,103 @@ import (
 	"bufio"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/agukrapo/go-http-client/client"
 	"github.com/agukrapo/playlist-creator/deezer"
+	"github.com/agukrapo/playlist-creator/internal/cache"
+	"github.com/agukrapo/playlist-creator/internal/credentials"
 	"github.com/agukrapo/playlist-creator/internal/env"
+	"github.com/agukrapo/playlist-creator/internal/inputs"
 	"github.com/agukrapo/playlist-creator/internal/logs"
-	"github.com/agukrapo/playlist-creator/internal/random"
 	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/agukrapo/playlist-creator/internal/retry"
+	"github.com/agukrapo/playlist-creator/mock"
 	"github.com/agukrapo/playlist-creator/playlists"
 	"github.com/agukrapo/playlist-creator/spotify"
 )
 
-const appTitle = "playlist-creator-cli"
+const (
+	appTitle = "playlist-creator-cli"
+	appName  = "playlist-creator"
+)
+
+// Exit codes, besides 0 on success and 1 on any other error.
+const (
+	exitNotFound = 2 // the playlist was pushed, but some tracks were not found, skipped or failed
+	exitAuth     = 3 // missing or rejected credentials
+	exitPush     = 4 // creating or updating the playlist failed
+)
+
+// exitError sets the process exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
 
 func main() {
-	if err := run(); err != nil {
+	err := run()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+
+	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+func exitCode(err error) int {
+	if errors.Is(err, playlists.ErrUnauthorized) || errors.Is(err, spotify.ErrNotLoggedIn) {
+		return exitAuth
+	}
+
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
 	}
+
+	return 1
+}
+
+// app holds the state shared by the commands, the flags are set by each command.
+type app struct {
+	creds credentials.Backend
+	log   *logs.Logger
+
+	yes         bool // never prompt
+	dryRun      bool // stop before pushing
+	interactive bool // pick between matches
+	concurrency int  // concurrent searches
+	format      string
+
+	depth  int    // search results per track, zero for the target default
+	market string // Spotify search market
+
+	unavailable playlists.Availability // unplayable matches policy
+
+	noCache    bool // search without the cache
+	clearCache bool // empty the cache before searching
+	cacheTTL   time.Duration
+
+	public        bool
+	collaborative bool
+	description   string
+	cover         string // JPEG file path
+	resume        bool   // continue the last failed push
 }
 
 func run() error {
@@ -39,101 +113,156 @@ func run() error {
 	}
 	defer logFile.Close()
 
-	manager, err := buildManager(logs.New(logFile))
+	creds, err := credentials.Default()
 	if err != nil {
 		return err
 	}
 
-	lines, name, err := openFile()
-	if err != nil {
-		return err
-	}
-
-	if v, _ := env.Lookup[bool]("APPEND_RANDOM_NAME"); v {
-		name += " " + random.Name(20)
+	a := &app{
+		creds:    creds,
+		log:      logs.New(logFile),
+		cacheTTL: cache.DefaultTTL,
 	}
 
-	data := results.New(len(lines))
+	return a.dispatch(ctx, os.Args[1:])
+}
 
-	if err := manager.Gather(ctx, lines, func(i int, item results.Item, matches []playlists.Track) {
-		if len(matches) == 0 {
-			warn(fmt.Sprintf("%q: %s", item.Query(), playlists.ErrTrackNotFound))
-			return
-		}
-		track := matches[0]
-		if ok, _ := data.Put(i, item.WithID(track.ID).WithName(track.Name).WithActive(true)); !ok {
-			warn(fmt.Sprintf("Duplicated  for %q: id %s, name %q", item.Query(), track.ID, track.Name))
-		}
-	}); err != nil {
-		return err
+// buildTarget returns the named target, caching its searches unless disabled.
+func (a *app) buildTarget(ctx context.Context, name string) (playlists.Target, error) {
+	target, err := a.newTarget(ctx, name)
+	if err != nil || a.noCache || name == "mock" { // the mock catalog is local already
+		return target, err
 	}
 
-	songs, _ := data.Slice()
-	fmt.Printf("\nCreating playlist %q with %d tracks\n\n", name, len(songs))
-	fmt.Println("Press the Enter Key to continue")
-
-	if _, err := fmt.Scanln(); err != nil {
-		return err
+	dir, err := cache.Dir()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := manager.Push(ctx, name, songs); err != nil {
-		return err
+	if a.clearCache {
+		if err := cache.Clear(dir); err != nil {
+			return nil, err
+		}
 	}
 
-	fmt.Println("Playlist created")
+	if a.depth != 0 || a.market != "" {
+		// searches with other depth or market find other results
+		dir = filepath.Join(dir, fmt.Sprintf("depth-%d-market-%s", a.depth, a.market))
+	}
 
-	return nil
+	return cache.New(target, dir, a.cacheTTL), nil
 }
 
-func buildManager(log *logs.Logger) (*playlists.Manager, error) {
-	if len(os.Args) < 2 {
-		return nil, errors.New("target argument missing")
-	}
-
-	var target playlists.Target
-	switch os.Args[1] {
+func (a *app) newTarget(ctx context.Context, name string) (playlists.Target, error) {
+	switch name {
 	case "spotify":
-		token, err := env.Lookup[string]("SPOTIFY_TOKEN")
+		c, err := a.spotifyTarget(ctx)
 		if err != nil {
 			return nil, err
 		}
-		target = spotify.New(client.New(), token)
+		if a.depth != 0 {
+			c.SetSearchDepth(a.depth)
+		}
+		c.SetMarket(a.market)
+		return c, nil
 	case "deezer":
-		cookie, err := env.Lookup[string]("DEEZER_ARL_COOKIE")
+		cookie, err := credentials.Lookup(a.creds, "DEEZER_ARL_COOKIE", "deezer", "arl")
 		if err != nil {
-			return nil, err
+			return nil, &exitError{code: exitAuth, err: err}
+		}
+		c := deezer.New(retry.Client("deezer"), cookie, a.log)
+		if a.depth != 0 {
+			c.SetSearchDepth(a.depth)
 		}
-		target = deezer.New(client.New(), cookie, log)
+		return c, nil
+	case "mock":
+		return mockTarget()
+	case "":
+		return nil, errors.New("target missing")
 	default:
-		return nil, fmt.Errorf("unknown target %s", os.Args[1])
+		return nil, fmt.Errorf("unknown target %s", name)
+	}
+}
+
+// mockTarget serves the catalog set by MOCK_CATALOG, recording its playlists in MOCK_DIR
+// or under the user cache directory.
+func mockTarget() (*mock.Target, error) {
+	catalog, _ := env.Lookup[string]("MOCK_CATALOG")
+	if catalog == "" {
+		return nil, errors.New("MOCK_CATALOG missing")
 	}
 
-	return playlists.NewManager(target, 100), nil
+	dir, _ := env.Lookup[string]("MOCK_DIR")
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(cacheDir, appName, "mock")
+	}
+
+	return mock.New(catalog, dir), nil
 }
 
-func openFile() ([]results.Item, string, error) {
-	if len(os.Args) < 3 {
-		return nil, "", errors.New("filename argument missing")
+func (a *app) spotifyTarget(ctx context.Context) (*spotify.Client, error) {
+	// unlike the other secrets, SPOTIFY_TOKEN isn't stored: it expires within an hour and can't be refreshed,
+	// so a stored one would only fail the following runs instead of falling back to the login
+	if token, _ := env.Lookup[string]("SPOTIFY_TOKEN"); token != "" {
+		return spotify.New(retry.Client("spotify"), token), nil
 	}
 
-	path := os.Args[2]
+	auth, err := a.spotifyAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.Token(ctx); errors.Is(err, spotify.ErrNotLoggedIn) && !a.yes {
+		if err := spotifyLogin(ctx, auth); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, &exitError{code: exitAuth, err: err}
+	}
+
+	return spotify.NewWithAuthenticator(retry.Client("spotify"), auth), nil
+}
 
-	file, err := os.Open(filepath.Clean(path))
+func (a *app) spotifyAuth() (*spotify.Authenticator, error) {
+	clientID, err := credentials.Lookup(a.creds, "SPOTIFY_CLIENT_ID", "spotify", "client_id")
 	if err != nil {
-		return nil, "", err
+		return nil, &exitError{code: exitAuth, err: err}
 	}
-	defer file.Close()
 
-	var lines []results.Item
+	redirectURI, _ := env.Lookup[string]("SPOTIFY_REDIRECT_URI")
+	if redirectURI == "" {
+		redirectURI = "http://127.0.0.1:8888/callback"
+	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if line := strings.TrimSpace(scanner.Text()); line != "" {
-			lines = append(lines, results.ParseItem(line))
-		}
+	return spotify.NewAuthenticator(retry.Client("spotify-accounts"), clientID, redirectURI, spotify.NewTokenStore(a.creds)), nil
+}
+
+func spotifyLogin(ctx context.Context, auth *spotify.Authenticator) error {
+	if err := auth.Login(ctx, func(u string) error {
+		fmt.Printf("Open the following URL to log in to Spotify:\n\n%s\n\n", u)
+		return nil
+	}); err != nil {
+		return &exitError{code: exitAuth, err: fmt.Errorf("spotify login: %w", err)}
 	}
 
-	if err = scanner.Err(); err != nil {
+	return nil
+}
+
+// openFile reads the items of the file at path, warning about the entries skipped, and returns them along with its name.
+func (a *app) openFile(path string) ([]results.Item, string, error) {
+	lines, err := inputs.Open(path, a.format)
+
+	var serr *inputs.SkippedError
+	if errors.As(err, &serr) {
+		warn(err)
+		err = nil
+	}
+
+	if err != nil {
 		return nil, "", err
 	}
 
@@ -142,6 +271,55 @@ func openFile() ([]results.Item, string, error) {
 	return lines, name, nil
 }
 
+// playlistOptions reads the cover image, if any, and validates the new playlist options.
+func (a *app) playlistOptions() (playlists.PlaylistOptions, error) {
+	out := playlists.PlaylistOptions{
+		Public:        a.public,
+		Collaborative: a.collaborative,
+		Description:   a.description,
+	}
+
+	if a.cover != "" {
+		cover, err := os.ReadFile(filepath.Clean(a.cover))
+		if err != nil {
+			return playlists.PlaylistOptions{}, err
+		}
+		out.Cover = cover
+	}
+
+	if err := out.Validate(); err != nil {
+		return playlists.PlaylistOptions{}, err
+	}
+
+	return out, nil
+}
+
+func writeLock(path string, items []results.Item) error {
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	if err := results.WriteLock(file, items); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// stdin is shared by the picker and the prompts.
+var stdin = bufio.NewReader(os.Stdin)
+
+func confirm(msg string) error {
+	fmt.Printf("\n%s\n\n", msg)
+	fmt.Println("Press the Enter Key to continue")
+
+	_, err := stdin.ReadString('\n')
+
+	return err
+}
+
 func warn(msg any) {
 	_, _ = fmt.Fprintln(os.Stderr, msg)
 }
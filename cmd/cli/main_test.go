@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agukrapo/playlist-creator/internal/cache"
+	"github.com/agukrapo/playlist-creator/internal/logs"
+	"github.com/agukrapo/playlist-creator/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setup points the mock target and the user cache to temporary directories, returning the mock one.
+func setup(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("MOCK_CATALOG", "../../mock/test-data/catalog.json")
+	t.Setenv("MOCK_DIR", dir)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	return dir
+}
+
+func dispatch(args ...string) error {
+	a := &app{log: logs.New(nil), cacheTTL: cache.DefaultTTL}
+	return a.dispatch(context.Background(), args)
+}
+
+func readPlaylist(t *testing.T, path string) mock.Playlist {
+	t.Helper()
+
+	bytes, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var out mock.Playlist
+	require.NoError(t, json.Unmarshal(bytes, &out))
+
+	return out
+}
+
+func TestCreate(t *testing.T) {
+	dir := setup(t)
+
+	err := dispatch("create", "-target", "mock", "-yes", "-description", "_DESCRIPTION", "test-data/songs.txt")
+	assert.Equal(t, exitNotFound, exitCode(err), "a track is missing")
+
+	p := readPlaylist(t, filepath.Join(dir, "playlist-2.json"))
+	assert.Equal(t, "songs", p.Name)
+	assert.Equal(t, "_DESCRIPTION", p.Description)
+	assert.Equal(t, []string{"2", "3"}, p.Tracks, "the unavailable live version is ranked last")
+
+	err = dispatch("-yes", "mock", "test-data/songs.txt", "append", "playlist-2")
+	assert.Equal(t, exitNotFound, exitCode(err))
+	assert.Equal(t, []string{"2", "3"}, readPlaylist(t, filepath.Join(dir, "playlist-2.json")).Tracks, "nothing is missing")
+}
+
+func TestCreate_dryRun(t *testing.T) {
+	dir := setup(t)
+
+	err := dispatch("create", "-target", "mock", "-dry-run", "test-data/songs.txt")
+	assert.Equal(t, exitNotFound, exitCode(err))
+
+	recorded, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, recorded)
+}
+
+func TestResolve_push(t *testing.T) {
+	dir := setup(t)
+	lock := filepath.Join(t.TempDir(), "songs.lock")
+
+	err := dispatch("resolve", "-target", "mock", "-yes", "-unavailable", "keep", "-output", lock, "test-data/songs.txt")
+	assert.Equal(t, exitNotFound, exitCode(err))
+
+	err = dispatch("push", "-yes", "-name", "Locked", lock)
+	assert.Equal(t, exitNotFound, exitCode(err), "the unresolved track is skipped")
+
+	p := readPlaylist(t, filepath.Join(dir, "playlist-2.json"))
+	assert.Equal(t, "Locked", p.Name)
+	assert.Len(t, p.Tracks, 2)
+}
+
+func TestPush_target(t *testing.T) {
+	table := []struct {
+		name          string
+		lock          string
+		expectedError string
+	}{
+		{
+			name:          "legacy",
+			lock:          ">>LOCKED§1§Tahitian Moon§porno for pyros tahitian moon\n",
+			expectedError: "the lock file names no target, set -target",
+		},
+		{
+			name: "mixed",
+			lock: `{"version":1}
+{"target":"mock","id":"1","name":"Tahitian Moon","query":"porno for pyros tahitian moon"}
+{"target":"spotify","id":"spotify:track:1","name":"Pets","query":"porno for pyros pets"}
+`,
+			expectedError: "the lock file mixes mock and spotify tracks, set -target",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			lock := filepath.Join(t.TempDir(), "songs.lock")
+			require.NoError(t, os.WriteFile(lock, []byte(test.lock), 0o600))
+
+			err := dispatch("push", "-yes", lock)
+			require.EqualError(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	dir := setup(t)
+
+	require.NoError(t, dispatch("transfer", "-from", "mock", "-to", "mock", "-yes", "-name", "Copy", "Favorites"))
+
+	p := readPlaylist(t, filepath.Join(dir, "playlist-2.json"))
+	assert.Equal(t, "Copy", p.Name)
+	assert.Equal(t, []string{"1", "3"}, p.Tracks)
+}
+
+func TestTarget_unknown(t *testing.T) {
+	setup(t)
+
+	err := dispatch("search", "-target", "other", "queen")
+	require.EqualError(t, err, "unknown target other")
+	assert.Equal(t, 1, exitCode(err))
+}
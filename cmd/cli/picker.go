@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/agukrapo/playlist-creator/playlists"
+)
+
+// candidate is a searched item and its ranked matches.
+type candidate struct {
+	i       int
+	item    results.Item
+	matches []playlists.Match
+}
+
+// picker walks through the items with several matches, letting the user choose one.
+type picker struct {
+	manager *playlists.Manager
+	in      *bufio.Reader
+	out     io.Writer
+}
+
+const pickerHelp = "number: pick, Enter or l: lock current, s: skip, r <query>: search again, a: keep the remaining ones"
+
+// pick asks for every candidate, total being the number of items, and puts the decisions in data.
+// data is expected to hold the best match of every candidate already.
+func (p *picker) pick(ctx context.Context, data *results.Set, total int, candidates []candidate) error {
+	_, _ = fmt.Fprintf(p.out, "\n%d tracks with several matches, %s\n", len(candidates), pickerHelp)
+
+	for _, c := range candidates {
+		rest, err := p.decide(ctx, data, total, c)
+		if err != nil {
+			return err
+		}
+
+		if rest {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// decide asks until the candidate is locked or skipped, reports true when the remaining ones must be kept.
+func (p *picker) decide(ctx context.Context, data *results.Set, total int, c candidate) (bool, error) {
+	current := 0
+
+	for {
+		p.show(c, current, total)
+
+		line, err := p.in.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			return false, errors.New("picker: unexpected end of input")
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+		switch cmd {
+		case "", "l":
+			if p.lock(data, c, current) {
+				return false, nil
+			}
+		case "a":
+			if p.lock(data, c, current) {
+				return true, nil
+			}
+		case "s":
+			m := c.matches[current]
+			data.Put(c.i, c.item.WithID(m.ID).WithName(m.Name).WithActive(false))
+			return false, nil
+		case "r":
+			query := strings.TrimSpace(arg)
+			if query == "" {
+				_, _ = fmt.Fprintln(p.out, "missing query")
+				continue
+			}
+
+			item := c.item.WithQuery(query).WithFields("", "", "").WithISRC("").WithDuration(0)
+			matches, err := p.manager.Search(ctx, item)
+			if err != nil {
+				return false, err
+			}
+			if len(matches) == 0 {
+				_, _ = fmt.Fprintf(p.out, "%q: %s\n", query, playlists.ErrTrackNotFound)
+				continue
+			}
+
+			c.item, c.matches, current = item, matches, 0
+		default:
+			v, err := strconv.Atoi(cmd)
+			if err != nil || v < 1 || v > len(c.matches) {
+				_, _ = fmt.Fprintln(p.out, pickerHelp)
+				continue
+			}
+
+			current = v - 1
+			if p.lock(data, c, current) {
+				return false, nil
+			}
+		}
+	}
+}
+
+func (p *picker) show(c candidate, current, total int) {
+	_, _ = fmt.Fprintf(p.out, "\n[%d/%d] %q\n", c.i+1, total, c.item.Query())
+
+	for j, m := range c.matches {
+		marker := " "
+		if j == current {
+			marker = "*"
+		}
+		_, _ = fmt.Fprintf(p.out, "%s %2d) %3.0f%% %s%s\n", marker, j+1, m.Score*100, m.Name, unavailable(m))
+	}
+
+	_, _ = fmt.Fprint(p.out, "> ")
+}
+
+// lock puts the chosen match of the candidate, reports false when it is already chosen for another item.
+func (p *picker) lock(data *results.Set, c candidate, chosen int) bool {
+	m := c.matches[chosen]
+
+	if ok, addedAt := data.Put(c.i, c.item.WithID(m.ID).WithName(m.Name).WithActive(true)); !ok {
+		_, _ = fmt.Fprintf(p.out, "%q already chosen for track %d\n", m.Name, addedAt+1)
+		return false
+	}
+
+	return true
+}
+
+// unavailable marks the matches the target can't play.
+func unavailable(m playlists.Match) string {
+	if m.Unavailable {
+		return " (unavailable)"
+	}
+
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -13,13 +14,17 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/agukrapo/go-http-client/client"
 	"github.com/agukrapo/playlist-creator/deezer"
+	"github.com/agukrapo/playlist-creator/internal/cache"
+	"github.com/agukrapo/playlist-creator/internal/credentials"
+	"github.com/agukrapo/playlist-creator/internal/inputs"
 	"github.com/agukrapo/playlist-creator/internal/logs"
 	"github.com/agukrapo/playlist-creator/internal/random"
 	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/agukrapo/playlist-creator/internal/retry"
 	"github.com/agukrapo/playlist-creator/playlists"
 )
 
@@ -30,12 +35,13 @@ type application struct {
 
 	dialogs chan dialoger
 
+	creds  credentials.Backend
 	cookie string
 
 	log *logs.Logger
 }
 
-func newApplication(cookie string, log *logs.Logger) *application {
+func newApplication(creds credentials.Backend, cookie string, log *logs.Logger) *application {
 	out := fyneapp.New()
 
 	version := out.Metadata().Custom["version"]
@@ -49,6 +55,7 @@ func newApplication(cookie string, log *logs.Logger) *application {
 	return &application{
 		window:  w,
 		dialogs: make(chan dialoger),
+		creds:   creds,
 		cookie:  cookie,
 		log:     log,
 	}
@@ -77,6 +84,9 @@ func (a *application) renderNewFormA() {
 		_ = songs.Validate() // force submit button to enable after a paste
 	}
 
+	// imported keeps the items of the opened file by their line, so the search uses their ISRC, album and duration
+	imported := make(map[string]results.Item)
+
 	reset := func() {
 		a.renderNewFormA()
 	}
@@ -95,18 +105,113 @@ func (a *application) renderNewFormA() {
 
 		a.working()
 
-		target := deezer.New(client.New(), arl.Text, a.log)
-		a.renderResults(target, name.Text, splitLines(songs.Text))
+		a.cookie = arl.Text
+		if err := a.creds.Set("deezer", "arl", a.cookie); err != nil {
+			a.notify(fmt.Sprintf("saving ARL: %v", err))
+		}
+
+		var target playlists.Target = deezer.New(retry.Client("deezer"), a.cookie, a.log)
+		if dir, err := cache.Dir(); err == nil {
+			target = cache.New(target, dir, cache.DefaultTTL)
+		}
+
+		a.renderResults(target, name.Text, splitLines(songs.Text, imported))
 	}
 
+	open := widget.NewButtonWithIcon("Open file", theme.FolderOpenIcon(), func() {
+		a.openFile(func(filename string, items []results.Item) {
+			clear(imported)
+
+			lines := make([]string, 0, len(items))
+			for _, item := range items {
+				lines = append(lines, item.String())
+				imported[item.String()] = item
+			}
+
+			name.SetText(filename)
+			songs.SetText(strings.Join(lines, "\n"))
+		})
+	})
+
 	form.Append("ARL", arl)
 	form.Append("Name", name)
 	form.Append("Songs", songs)
+	form.Append("Import", container.NewHBox(open))
 
 	a.window.SetContent(page("Playlist data", form))
 	a.formA = form
 }
 
+// openFile shows a file dialog and calls fn with the name, without extension, and items of the chosen file.
+func (a *application) openFile(fn func(name string, items []results.Item)) {
+	d := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil {
+			a.error(err)
+			return
+		}
+		if rc == nil {
+			return
+		}
+		defer rc.Close()
+
+		read, err := inputs.Format("", rc.URI().Name())
+		if err != nil {
+			a.error(err)
+			return
+		}
+
+		items, err := read(rc)
+
+		var serr *inputs.SkippedError
+		if errors.As(err, &serr) {
+			a.notify(fmt.Sprintf("%s: %v", rc.URI().Name(), err))
+			err = nil
+		}
+
+		if err != nil {
+			a.error(fmt.Errorf("%s: %w", rc.URI().Name(), err))
+			return
+		}
+
+		fn(strings.TrimSuffix(rc.URI().Name(), rc.URI().Extension()), items)
+	}, a.window)
+
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".txt", ".csv", ".json", ".m3u", ".m3u8", ".pls"}))
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
+
+// openCover asks for a JPEG image to be used as playlist cover.
+func (a *application) openCover(fn func(name string, image []byte)) {
+	d := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		if rc == nil {
+			return
+		}
+		defer rc.Close()
+
+		image, err := io.ReadAll(rc)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("%s: %w", rc.URI().Name(), err), a.window)
+			return
+		}
+
+		if err := (playlists.PlaylistOptions{Cover: image}).Validate(); err != nil {
+			dialog.ShowError(fmt.Errorf("%s: %w", rc.URI().Name(), err), a.window)
+			return
+		}
+
+		fn(rc.URI().Name(), image)
+	}, a.window)
+
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".jpg", ".jpeg"}))
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
+
 func (a *application) renderResults(target playlists.Target, name string, songs []results.Item) {
 	items := make([]*widget.FormItem, 0, len(songs))
 	for i, song := range songs {
@@ -144,8 +249,8 @@ func (a *application) renderResults(target playlists.Target, name string, songs
 		},
 	}
 
-	if err := manager.Gather(context.Background(), songs, func(i int, item results.Item, matches []playlists.Track) {
-		singleResult := func(item results.Item) {
+	if err := manager.Gather(context.Background(), songs, func(i int, item results.Item, res playlists.Result) {
+		singleResult := func(item results.Item, label string) {
 			check := widget.NewCheck("", nil)
 			check.OnChanged = func(v bool) {
 				item := item.WithActive(v)
@@ -161,30 +266,36 @@ func (a *application) renderResults(target playlists.Target, name string, songs
 
 			check.OnChanged(item.Active())
 
-			items[i].Widget = container.NewHBox(check, widget.NewLabel(item.Name()))
+			items[i].Widget = container.NewHBox(check, widget.NewLabel(label))
 		}
 
-		if item.Active() {
-			singleResult(item)
+		if res.Status == playlists.Found && len(res.Matches) == 0 {
+			singleResult(item, item.Name())
 			return
 		}
 
-		if len(matches) == 0 {
-			items[i].Widget = errorLabel(i+1, "not found")
+		if res.Status != playlists.Found {
+			msg := res.Status.String()
+			if res.Err != nil {
+				msg = res.Err.Error()
+			}
+
+			items[i].Widget = errorLabel(i+1, msg)
 			if ok, addedAt := data.Put(i, item); !ok {
 				a.notify(fmt.Sprintf("track %d: duplicated of track %d %q", i+1, addedAt+1, item.Name()))
 			}
 			return
 		}
 
+		matches := res.Matches
 		if len(matches) == 1 {
-			singleResult(item.WithID(matches[0].ID).WithName(matches[0].Name))
+			singleResult(item.WithID(matches[0].ID).WithName(matches[0].Name), confidence(matches[0]))
 			return
 		}
 
 		opts := make([]string, 0, len(matches))
-		for _, t := range matches {
-			opts = append(opts, t.Name)
+		for _, m := range matches {
+			opts = append(opts, confidence(m))
 		}
 
 		sel := widget.NewSelect(opts, nil)
@@ -224,6 +335,12 @@ func (a *application) renderResults(target playlists.Target, name string, songs
 	a.renderDialog(nothing{})
 }
 
+const (
+	modeCreate  = "Create new playlist"
+	modeAppend  = "Add missing tracks to existing playlist"
+	modeReplace = "Replace existing playlist tracks"
+)
+
 func (a *application) makeConfirm(manager *playlists.Manager, name string, data *results.Set) *dialog.FormDialog {
 	songs, excluded := data.Slice()
 
@@ -231,12 +348,50 @@ func (a *application) makeConfirm(manager *playlists.Manager, name string, data
 	nw.Validator = notEmpty("Name")
 	nw.SetText(name)
 
+	var opts playlists.PlaylistOptions
+
+	pw := widget.NewCheck("Public", func(v bool) { opts.Public = v })
+	cw := widget.NewCheck("Collaborative", func(v bool) { opts.Collaborative = v })
+	dw := widget.NewEntry()
+	dw.SetPlaceHolder("Optional")
+
+	coverLabel := widget.NewLabel("None")
+	coverButton := widget.NewButtonWithIcon("Open image", theme.FileImageIcon(), func() {
+		a.openCover(func(name string, image []byte) {
+			opts.Cover = image
+			coverLabel.SetText(name)
+		})
+	})
+
+	newOnly := []fyne.Disableable{pw, cw, dw, coverButton}
+
+	mw := widget.NewSelect([]string{modeCreate, modeAppend, modeReplace}, func(v string) {
+		if v == modeCreate {
+			nw.SetPlaceHolder("")
+		} else {
+			nw.SetPlaceHolder("Existing playlist name or ID")
+		}
+
+		for _, w := range newOnly {
+			if v == modeCreate {
+				w.Enable()
+			} else {
+				w.Disable()
+			}
+		}
+	})
+	mw.SetSelected(modeCreate)
+
 	ew := widget.NewMultiLineEntry()
 	ew.SetMinRowsVisible(10)
 	ew.Text = strings.Join(excluded, "\n")
 
 	items := []*widget.FormItem{
+		widget.NewFormItem("Mode", mw),
 		widget.NewFormItem("Name", nw),
+		widget.NewFormItem("Visibility", container.NewHBox(pw, cw)),
+		widget.NewFormItem("Description", dw),
+		widget.NewFormItem("Cover", container.NewHBox(coverButton, coverLabel)),
 		widget.NewFormItem("Tracks", widget.NewLabel(strconv.Itoa(len(songs)))),
 		widget.NewFormItem("Excluded", ew),
 	}
@@ -248,7 +403,24 @@ func (a *application) makeConfirm(manager *playlists.Manager, name string, data
 
 		a.working()
 
-		if err := manager.Push(context.Background(), nw.Text, songs); err != nil {
+		var err error
+		switch mw.Selected {
+		case modeAppend:
+			err = manager.Update(context.Background(), nw.Text, songs, false)
+		case modeReplace:
+			err = manager.Update(context.Background(), nw.Text, songs, true)
+		default:
+			opts.Description = dw.Text
+			err = manager.Push(context.Background(), nw.Text, songs, opts, nil)
+		}
+
+		var cerr *playlists.CoverError
+		if errors.As(err, &cerr) {
+			a.notify(err.Error())
+			err = nil
+		}
+
+		if err != nil {
 			a.error(err)
 			return
 		}
@@ -257,11 +429,18 @@ func (a *application) makeConfirm(manager *playlists.Manager, name string, data
 		a.renderDialog(nothing{})
 	}, a.window)
 
-	out.Resize(fyne.NewSize(600, 400))
+	out.Resize(fyne.NewSize(600, 500))
 
 	return out
 }
 
+func confidence(m playlists.Match) string {
+	if m.Unavailable {
+		return fmt.Sprintf("[%.0f%%] %s (unavailable)", m.Score*100, m.Name)
+	}
+	return fmt.Sprintf("[%.0f%%] %s", m.Score*100, m.Name)
+}
+
 func errorLabel(trackNumber int, msg string) fyne.CanvasObject {
 	_, _ = fmt.Fprintf(os.Stderr, "Error: track %d: %s\n", trackNumber, msg)
 	return container.NewHBox(widget.NewIcon(theme.ErrorIcon()),
@@ -277,7 +456,8 @@ func notEmpty(name string) func(v string) error {
 	}
 }
 
-func splitLines(in string) []results.Item {
+// splitLines parses an item per distinct line, taking the imported one when the line is unchanged.
+func splitLines(in string, imported map[string]results.Item) []results.Item {
 	var out []results.Item
 
 	dedup := make(map[string]struct{})
@@ -292,6 +472,11 @@ func splitLines(in string) []results.Item {
 		}
 		dedup[s] = struct{}{}
 
+		if item, ok := imported[s]; ok {
+			out = append(out, item)
+			continue
+		}
+
 		out = append(out, results.ParseItem(s))
 	}
 	return out
@@ -4,16 +4,22 @@ import (
 	"os"
 
 	"fyne.io/fyne/v2"
-	"github.com/agukrapo/playlist-creator/internal/env"
+	"github.com/agukrapo/playlist-creator/internal/credentials"
 	"github.com/agukrapo/playlist-creator/internal/logs"
 )
 
 const appTitle = "playlist-creator"
 
 func main() {
-	cookie, err := env.Lookup[string]("DEEZER_ARL_COOKIE")
+	creds, err := credentials.Default()
 	if err != nil {
-		fyne.LogError("env.Lookup", err)
+		fyne.LogError("credentials.Default", err)
+		os.Exit(1)
+	}
+
+	cookie, err := credentials.Lookup(creds, "DEEZER_ARL_COOKIE", "deezer", "arl")
+	if err != nil {
+		fyne.LogError("credentials.Lookup", err)
 	}
 
 	logFile, err := logs.NewFile(appTitle)
@@ -23,6 +29,6 @@ func main() {
 	}
 	defer logFile.Close()
 
-	app := newApplication(cookie, logs.New(logFile))
+	app := newApplication(creds, cookie, logs.New(logFile))
 	app.ShowAndRun()
 }
@@ -1,16 +1,19 @@
 package deezer
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/agukrapo/go-http-client/requests"
@@ -26,10 +29,14 @@ type doer interface {
 type Client struct {
 	httpClient doer
 	apiURL     string
+	publicURL  string
+	uploadURL  string
 
 	tokenizer func(ctx context.Context) (string, cookieJar, error)
 
-	arl string
+	arl    string
+	userID atomic.Uint64
+	depth  int // maximum search results
 
 	log *logs.Logger
 }
@@ -38,7 +45,10 @@ func New(httpClient doer, arl string, log *logs.Logger) *Client {
 	out := &Client{
 		httpClient: httpClient,
 		apiURL:     "https://www.deezer.com/ajax/gw-light.php",
+		publicURL:  "https://api.deezer.com",
+		uploadURL:  "https://upload.deezer.com",
 		arl:        arl,
+		depth:      100,
 		log:        log,
 	}
 
@@ -47,10 +57,21 @@ func New(httpClient doer, arl string, log *logs.Logger) *Client {
 	return out
 }
 
+// SetSearchDepth sets the maximum number of results of every search.
+func (c *Client) SetSearchDepth(depth int) {
+	c.depth = max(depth, 1)
+}
+
 func (c *Client) Name() string {
 	return "deezer"
 }
 
+// ValidID tells if the ID is a song number.
+func (c *Client) ValidID(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil && validID(id)
+}
+
 func (c *Client) Setup(context.Context) error {
 	return nil
 }
@@ -75,26 +96,31 @@ func (c *Client) token(ctx context.Context) (token string, cookies cookieJar, er
 	}
 
 	if out.User.ID == 0 {
-		return "", nil, errors.New("invalid arl cookie")
+		return "", nil, fmt.Errorf("%w: invalid arl cookie", playlists.ErrUnauthorized)
 	}
 
+	c.userID.Store(uint64(out.User.ID))
+
 	return out.CheckForm, cookies, nil
 }
 
 type duration string
 
-func (d duration) String() string {
+func (d duration) value() time.Duration {
 	v, err := strconv.Atoi(string(d))
 	if err != nil {
-		return ""
+		return 0
 	}
 
-	dur, err := time.ParseDuration(fmt.Sprintf("%ds", v))
-	if err != nil {
+	return time.Duration(v) * time.Second
+}
+
+func (d duration) String() string {
+	if _, err := strconv.Atoi(string(d)); err != nil {
 		return ""
 	}
 
-	return time.Unix(0, 0).UTC().Add(dur).Format("04:05")
+	return time.Unix(0, 0).UTC().Add(d.value()).Format("04:05")
 }
 
 type album struct {
@@ -118,20 +144,82 @@ func (a *album) String() string {
 	return d + a.Title
 }
 
+type artist struct {
+	Name string `json:"ART_NAME"`
+}
+
+type song struct {
+	SongID     string   `json:"SNG_ID"`
+	Title      string   `json:"SNG_TITLE"`
+	Duration   duration `json:"DURATION"`
+	Version    string   `json:"VERSION"`
+	Artist     string   `json:"ART_NAME"`
+	Artists    []artist `json:"ARTISTS"`
+	AlbumID    string   `json:"ALB_ID"`
+	AlbumTitle string   `json:"ALB_TITLE"`
+	ISRC       string   `json:"ISRC"`
+	Readable   *bool    `json:"READABLE"`
+	Rights     *rights  `json:"RIGHTS"`
+}
+
+// available tells if the song can be streamed, songs without the flags are assumed to be.
+func (s song) available() bool {
+	if s.Readable != nil && !*s.Readable {
+		return false
+	}
+
+	return s.Rights == nil || s.Rights.StreamAds || s.Rights.StreamSub
+}
+
+// rights are the streaming rights of a song, sent as an empty array when there are none.
+type rights struct {
+	StreamAds bool `json:"STREAM_ADS_AVAILABLE"`
+	StreamSub bool `json:"STREAM_SUB_AVAILABLE"`
+}
+
+func (r *rights) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		*r = rights{}
+		return nil
+	}
+
+	type plain rights
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+func (s song) track(albums map[string]*album) playlists.Track {
+	artists := []string{s.Artist}
+	for _, a := range s.Artists {
+		artists = append(artists, a.Name)
+	}
+	artists = slices.Compact(artists)
+
+	title := s.Title
+	if s.Version != "" {
+		title += " " + s.Version
+	}
+
+	alb := albums[s.AlbumID].String()
+	if alb == "" {
+		alb = s.AlbumTitle
+	}
+
+	return playlists.Track{
+		ID:       s.SongID,
+		Name:     fmt.Sprintf("%s - %s [%s] %s", strings.Join(artists, ", "), title, s.Duration, alb),
+		Title:    title,
+		Artists:  artists,
+		Album:    s.AlbumTitle,
+		ISRC:     s.ISRC,
+		Duration: s.Duration.value(),
+
+		Unavailable: !s.available(),
+	}
+}
+
 type searchResponse struct {
 	Track struct {
-		Data []struct {
-			SongID   string   `json:"SNG_ID"`
-			Title    string   `json:"SNG_TITLE"`
-			Duration duration `json:"DURATION"`
-			Version  string   `json:"VERSION"`
-			Artist   string   `json:"ART_NAME"`
-			Artists  []struct {
-				Name string `json:"ART_NAME"`
-			} `json:"ARTISTS"`
-			AlbumID    string `json:"ALB_ID"`
-			AlbumTitle string `json:"ALB_TITLE"`
-		} `json:"data"`
+		Data []song `json:"data"`
 	} `json:"TRACK"`
 	Album struct {
 		Data []album `json:"data"`
@@ -150,31 +238,16 @@ func (sr searchResponse) tracks() []playlists.Track {
 			continue
 		}
 
-		artists := []string{t.Artist}
-		for _, a := range t.Artists {
-			artists = append(artists, a.Name)
-		}
-		artist := strings.Join(slices.Compact(artists), ", ")
-
-		title := t.Title
-		if t.Version != "" {
-			title += " " + t.Version
-		}
-
-		alb := albums[t.AlbumID].String()
-		if alb == "" {
-			alb = t.AlbumTitle
-		}
-
-		out = append(out, playlists.Track{
-			ID:   t.SongID,
-			Name: fmt.Sprintf("%s - %s [%s] %s", artist, title, t.Duration, alb),
-		})
+		out = append(out, t.track(albums))
 	}
 	return out
 }
 
 func (c *Client) SearchTracks(ctx context.Context, query string) (tracks []playlists.Track, err error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
 	tr := c.log.Trace("deezer.SearchTracks").Begins(logs.Var("query", query))
 	defer func() { tr.Ends(err, logs.Var("tracks", tracks)) }()
 
@@ -184,7 +257,7 @@ func (c *Client) SearchTracks(ctx context.Context, query string) (tracks []playl
 	}
 
 	in := map[string]any{
-		"nb":    100,
+		"nb":    c.depth,
 		"query": query,
 	}
 
@@ -196,8 +269,108 @@ func (c *Client) SearchTracks(ctx context.Context, query string) (tracks []playl
 	return out.tracks(), nil
 }
 
-func (c *Client) CreatePlaylist(ctx context.Context, title string) (id string, err error) {
-	tr := c.log.Trace("deezer.CreatePlaylist").Begins(logs.Var("title", title))
+// SearchFields searches by artist and title, the album is left out as the search does not support filters.
+func (c *Client) SearchFields(ctx context.Context, fields playlists.Fields) ([]playlists.Track, error) {
+	return c.SearchTracks(ctx, strings.TrimSpace(fields.Artist+" "+fields.Title))
+}
+
+type isrcResponse struct {
+	ID           json.Number `json:"id"`
+	Title        string      `json:"title"`
+	ISRC         string      `json:"isrc"`
+	Duration     json.Number `json:"duration"`
+	Contributors []struct {
+		Name string `json:"name"`
+	} `json:"contributors"`
+	Artist struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Album struct {
+		Title string `json:"title"`
+	} `json:"album"`
+	Readable *bool `json:"readable"`
+	Error    *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// dataNotFound is the public API error code for unknown resources.
+const dataNotFound = 800
+
+// SearchISRC looks up the track with the given ISRC code in the public API.
+func (c *Client) SearchISRC(ctx context.Context, isrc string) (tracks []playlists.Track, err error) {
+	if isrc == "" {
+		return nil, nil
+	}
+
+	tr := c.log.Trace("deezer.SearchISRC").Begins(logs.Var("isrc", isrc))
+	defer func() { tr.Ends(err, logs.Var("tracks", tracks)) }()
+
+	req, err := requests.New(c.publicURL + "/track/isrc:" + url.PathEscape(isrc)).Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	tr.Dump("track/isrc", raw)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", res.Status, raw)
+	}
+
+	var out isrcResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+
+	if out.Error != nil {
+		if out.Error.Code == dataNotFound {
+			return nil, nil
+		}
+		return nil, uncapitalize(out.Error.Message)
+	}
+
+	s := song{
+		SongID:     out.ID.String(),
+		Title:      out.Title,
+		Duration:   duration(out.Duration.String()),
+		Artist:     out.Artist.Name,
+		AlbumTitle: out.Album.Title,
+		ISRC:       out.ISRC,
+		Readable:   out.Readable,
+	}
+	for _, c := range out.Contributors {
+		s.Artists = append(s.Artists, artist{Name: c.Name})
+	}
+
+	if !validID(s.SongID) {
+		return nil, nil
+	}
+
+	return []playlists.Track{s.track(nil)}, nil
+}
+
+// Playlist statuses of playlist.create.
+const (
+	statusPublic        = 0
+	statusPrivate       = 1
+	statusCollaborative = 2
+)
+
+func (c *Client) CreatePlaylist(ctx context.Context, title string, opts playlists.PlaylistOptions) (id string, err error) {
+	tr := c.log.Trace("deezer.CreatePlaylist").Begins(logs.Var("title", title), logs.Var("public", opts.Public),
+		logs.Var("collaborative", opts.Collaborative), logs.Var("description", opts.Description), logs.Var("cover", len(opts.Cover)))
 	defer func() { tr.Ends(err, logs.Var("id", id)) }()
 
 	token, cookies, err := c.tokenizer(ctx)
@@ -205,7 +378,19 @@ func (c *Client) CreatePlaylist(ctx context.Context, title string) (id string, e
 		return "", err
 	}
 
-	in := map[string]any{"title": title}
+	status := statusPrivate
+	switch {
+	case opts.Collaborative:
+		status = statusCollaborative
+	case opts.Public:
+		status = statusPublic
+	}
+
+	in := map[string]any{
+		"title":       title,
+		"description": opts.Description,
+		"status":      status,
+	}
 
 	var out json.Number
 	if _, err := c.send(ctx, tr, token, "playlist.create", cookies, in, &out); err != nil {
@@ -216,11 +401,80 @@ func (c *Client) CreatePlaylist(ctx context.Context, title string) (id string, e
 		return "", errors.New("failed to create playlist")
 	}
 
+	if len(opts.Cover) != 0 {
+		if err := c.uploadPicture(ctx, tr, cookies, out.String(), opts.Cover); err != nil {
+			return out.String(), &playlists.CoverError{Err: fmt.Errorf("upload picture: %w", err)}
+		}
+	}
+
 	return out.String(), nil
 }
 
-func (c *Client) PopulatePlaylist(ctx context.Context, playlist string, tracks []string) (err error) {
-	tr := c.log.Trace("deezer.PopulatePlaylist").Begins(logs.Var("playlist", playlist), logs.Var("tracks", tracks))
+// uploadPicture sets the playlist picture, sending the JPEG image as a multipart form.
+func (c *Client) uploadPicture(ctx context.Context, tr *logs.Trace, cookies cookieJar, playlist string, image []byte) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	part, err := form.CreateFormFile("file", "cover.jpg")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(image); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := requests.New(c.uploadURL+"/playlist/"+playlist).Post().Body(&body).Header("Content-Type", form.FormDataContentType()).Build(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	tr.Dump("upload.playlist", raw)
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", res.Status, raw)
+	}
+
+	var out struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+
+	if out.Error != nil {
+		return uncapitalize(out.Error.Message)
+	}
+
+	return nil
+}
+
+// maxSongsPerRequest bounds the number of songs sent in a single playlist.addSongs call.
+const maxSongsPerRequest = 50
+
+// PopulatePlaylist adds the tracks to the playlist from the given position on, in batches of up to 50 songs.
+// The gateway places each song at the position sent along with it, so it must follow the tracks already there.
+func (c *Client) PopulatePlaylist(ctx context.Context, playlist string, position int, tracks []string) (err error) {
+	tr := c.log.Trace("deezer.PopulatePlaylist").Begins(logs.Var("playlist", playlist), logs.Var("position", position), logs.Var("tracks", tracks))
 	defer func() { tr.Ends(err) }()
 
 	token, cookies, err := c.tokenizer(ctx)
@@ -228,9 +482,24 @@ func (c *Client) PopulatePlaylist(ctx context.Context, playlist string, tracks [
 		return err
 	}
 
+	var added int
+	for chunk := range slices.Chunk(tracks, maxSongsPerRequest) {
+		if err := c.addSongs(ctx, tr, token, cookies, playlist, chunk, position+added); err != nil {
+			if added == 0 {
+				return playlistError(playlist, err)
+			}
+			return &playlists.PopulateError{Added: added, Err: err}
+		}
+		added += len(chunk)
+	}
+
+	return nil
+}
+
+func (c *Client) addSongs(ctx context.Context, tr *logs.Trace, token string, cookies cookieJar, playlist string, tracks []string, offset int) error {
 	songs := make([][]any, len(tracks))
 	for i, t := range tracks {
-		songs[i] = []any{t, i}
+		songs[i] = []any{t, offset + i}
 	}
 
 	in := map[string]any{
@@ -250,6 +519,120 @@ func (c *Client) PopulatePlaylist(ctx context.Context, playlist string, tracks [
 	return nil
 }
 
+type profileResponse struct {
+	Tab struct {
+		Playlists struct {
+			Data []struct {
+				ID    string `json:"PLAYLIST_ID"`
+				Title string `json:"TITLE"`
+			} `json:"data"`
+		} `json:"playlists"`
+	} `json:"TAB"`
+}
+
+func (c *Client) Playlists(ctx context.Context) (out []playlists.Playlist, err error) {
+	tr := c.log.Trace("deezer.Playlists").Begins()
+	defer func() { tr.Ends(err, logs.Var("playlists", out)) }()
+
+	token, cookies, err := c.tokenizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	in := map[string]any{
+		"USER_ID": c.userID.Load(),
+		"tab":     "playlists",
+		"nb":      -1,
+	}
+
+	var res profileResponse
+	if _, err := c.send(ctx, tr, token, "deezer.pageProfile", cookies, in, &res); err != nil {
+		return nil, err
+	}
+
+	for _, p := range res.Tab.Playlists.Data {
+		out = append(out, playlists.Playlist{ID: p.ID, Name: p.Title})
+	}
+
+	return out, nil
+}
+
+type songsResponse struct {
+	Data []song `json:"data"`
+}
+
+func (c *Client) PlaylistTracks(ctx context.Context, playlist string) (tracks []playlists.Track, err error) {
+	tr := c.log.Trace("deezer.PlaylistTracks").Begins(logs.Var("playlist", playlist))
+	defer func() { tr.Ends(err, logs.Var("tracks", tracks)) }()
+
+	token, cookies, err := c.tokenizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	in := map[string]any{
+		"playlist_id": playlist,
+		"nb":          -1,
+	}
+
+	var out songsResponse
+	if _, err := c.send(ctx, tr, token, "playlist.getSongs", cookies, in, &out); err != nil {
+		return nil, playlistError(playlist, err)
+	}
+
+	for _, s := range out.Data {
+		if validID(s.SongID) {
+			tracks = append(tracks, s.track(nil))
+		}
+	}
+
+	return tracks, nil
+}
+
+func (c *Client) RemoveTracks(ctx context.Context, playlist string, tracks []string) (err error) {
+	tr := c.log.Trace("deezer.RemoveTracks").Begins(logs.Var("playlist", playlist), logs.Var("tracks", tracks))
+	defer func() { tr.Ends(err) }()
+
+	token, cookies, err := c.tokenizer(ctx)
+	if err != nil {
+		return err
+	}
+
+	for chunk := range slices.Chunk(tracks, maxSongsPerRequest) {
+		songs := make([][]any, len(chunk))
+		for i, t := range chunk {
+			songs[i] = []any{t, 0}
+		}
+
+		in := map[string]any{
+			"playlist_id": playlist,
+			"songs":       songs,
+		}
+
+		var out bool
+		if _, err := c.send(ctx, tr, token, "playlist.deleteSongs", cookies, in, &out); err != nil {
+			return playlistError(playlist, err)
+		}
+
+		if !out {
+			return errors.New("failed to remove tracks")
+		}
+	}
+
+	return nil
+}
+
+// errNoData is wrapped by the gateway DATA_ERROR errors, answered for unknown IDs.
+var errNoData = errors.New("no data")
+
+// playlistError reports the gateway having no data for the playlist as playlists.ErrPlaylistNotFound.
+func playlistError(playlist string, err error) error {
+	if errors.Is(err, errNoData) {
+		return fmt.Errorf("%q: %w", playlist, playlists.ErrPlaylistNotFound)
+	}
+	return err
+}
+
 type envelope struct {
 	Error   any             `json:"error"`
 	Results json.RawMessage `json:"results"`
@@ -260,8 +643,12 @@ func (e envelope) asError() error {
 
 	switch t := e.Error.(type) {
 	case map[string]any:
-		for _, v := range t {
-			out = errors.Join(out, uncapitalize(v))
+		for k, v := range t {
+			err := uncapitalize(v)
+			if err != nil && k == "DATA_ERROR" {
+				err = fmt.Errorf("%w: %w", errNoData, err)
+			}
+			out = errors.Join(out, err)
 		}
 	case []any:
 		for _, v := range t {
@@ -2,14 +2,18 @@ package deezer
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/agukrapo/playlist-creator/internal/logs"
+	"github.com/agukrapo/playlist-creator/internal/retry"
 	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/agukrapo/playlist-creator/playlists"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -34,7 +38,8 @@ func TestClient_token(t *testing.T) {
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodPost, req.Method)
 				assert.Equal(t, "/?api_token=&api_version=1.0&method=deezer.getUserData", req.URL.String())
 				assert.Equal(t, "null", tests.ReadBody(t, req))
@@ -42,11 +47,10 @@ func TestClient_token(t *testing.T) {
 
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
 
 			client := New(http.DefaultClient, "_ARL", logs.New(nil))
-			client.apiURL = svr.URL
+			client.apiURL = api.URL
 
 			token, cookies, err := client.token(context.Background())
 			require.Equal(t, test.expectedError, tests.AsString(err))
@@ -79,7 +83,8 @@ func TestClient_SearchTrack(t *testing.T) {
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodPost, req.Method)
 				assert.Equal(t, "/?api_token=_TOKEN&api_version=1.0&method=deezer.pageSearch", req.URL.String())
 				assert.JSONEq(t, `{"nb":100, "query":"_QUERY"}`, tests.ReadBody(t, req))
@@ -87,11 +92,10 @@ func TestClient_SearchTrack(t *testing.T) {
 
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
 
 			client := New(http.DefaultClient, "_ARL", logs.New(nil))
-			client.apiURL = svr.URL
+			client.apiURL = api.URL
 			client.tokenizer = func(context.Context) (string, cookieJar, error) {
 				return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
 			}
@@ -112,6 +116,91 @@ func TestClient_SearchTrack(t *testing.T) {
 	}
 }
 
+func TestClient_CreatePlaylist(t *testing.T) {
+	table := []struct {
+		name           string
+		opts           playlists.PlaylistOptions
+		pictureStatus  int
+		pictureBody    string
+		expectedBody   string
+		expectedID     string
+		expectedError  string
+		expectsPicture bool
+	}{
+		{
+			name:         "private",
+			expectedBody: `{"title":"_TITLE","description":"","status":1}`,
+			expectedID:   "11856839981",
+		},
+		{
+			name:         "public",
+			opts:         playlists.PlaylistOptions{Public: true, Description: "_DESCRIPTION"},
+			expectedBody: `{"title":"_TITLE","description":"_DESCRIPTION","status":0}`,
+			expectedID:   "11856839981",
+		},
+		{
+			name:           "collaborative with picture",
+			opts:           playlists.PlaylistOptions{Collaborative: true, Cover: []byte{0xFF, 0xD8, 0xFF}},
+			pictureStatus:  http.StatusOK,
+			pictureBody:    `{"id":123}`,
+			expectedBody:   `{"title":"_TITLE","description":"","status":2}`,
+			expectedID:     "11856839981",
+			expectsPicture: true,
+		},
+		{
+			name:           "picture error",
+			opts:           playlists.PlaylistOptions{Cover: []byte{0xFF, 0xD8, 0xFF}},
+			pictureStatus:  http.StatusOK,
+			pictureBody:    `{"error":{"type":"OAuthException","message":"Invalid OAuth access token.","code":300}}`,
+			expectedBody:   `{"title":"_TITLE","description":"","status":1}`,
+			expectedID:     "11856839981",
+			expectedError:  "cover not uploaded: upload picture: invalid OAuth access token.",
+			expectsPicture: true,
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.handle("POST /playlist/11856839981", func(w http.ResponseWriter, req *http.Request) {
+				assert.True(t, test.expectsPicture)
+				assert.Equal(t, "_ARL", tests.ReadCookie(t, req, "arl"))
+
+				file, _, err := req.FormFile("file")
+				require.NoError(t, err)
+				defer file.Close()
+
+				picture, err := io.ReadAll(file)
+				require.NoError(t, err)
+				assert.Equal(t, test.opts.Cover, picture)
+
+				w.WriteHeader(test.pictureStatus)
+				_, err = w.Write([]byte(test.pictureBody))
+				assert.NoError(t, err)
+			})
+			api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "_ARL", tests.ReadCookie(t, req, "arl"))
+				assert.Equal(t, "/?api_token=_TOKEN&api_version=1.0&method=playlist.create", req.URL.String())
+				assert.JSONEq(t, test.expectedBody, tests.ReadBody(t, req))
+
+				_, err := w.Write([]byte(tests.ReadFile(t, "test-data/create_playlist_ok.json")))
+				assert.NoError(t, err)
+			})
+
+			client := New(http.DefaultClient, "_ARL", logs.New(nil))
+			client.apiURL = api.URL
+			client.uploadURL = api.URL
+			client.tokenizer = func(context.Context) (string, cookieJar, error) {
+				return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
+			}
+
+			id, err := client.CreatePlaylist(context.Background(), "_TITLE", test.opts)
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			assert.Equal(t, test.expectedID, id)
+		})
+	}
+}
+
 func TestClient_PopulatePlaylist(t *testing.T) {
 	table := []struct {
 		name          string
@@ -130,7 +219,8 @@ func TestClient_PopulatePlaylist(t *testing.T) {
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodPost, req.Method)
 				assert.Equal(t, "/?api_token=_TOKEN&api_version=1.0&method=playlist.addSongs", req.URL.String())
 				assert.JSONEq(t, `{"playlist_id":"_PLAYLIST_ID","songs":[["_TRACK_A",0]]}`, tests.ReadBody(t, req))
@@ -138,17 +228,296 @@ func TestClient_PopulatePlaylist(t *testing.T) {
 
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
+
+			client := New(http.DefaultClient, "_ARL", logs.New(nil))
+			client.apiURL = api.URL
+			client.tokenizer = func(context.Context) (string, cookieJar, error) {
+				return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
+			}
+
+			err := client.PopulatePlaylist(context.Background(), "_PLAYLIST_ID", 0, []string{"_TRACK_A"})
+			require.Equal(t, test.expectedError, tests.AsString(err))
+		})
+	}
+}
+
+func TestClient_PopulatePlaylist_throttled(t *testing.T) {
+	var calls int
+	api := newFakeAPI(t)
+	api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+		assert.JSONEq(t, `{"playlist_id":"_PLAYLIST_ID","songs":[["_TRACK_A",0]]}`, tests.ReadBody(t, req))
+
+		if calls++; calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/populate_playlist_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := New(retry.New(http.DefaultClient), "_ARL", logs.New(nil))
+	client.apiURL = api.URL
+	client.tokenizer = func(context.Context) (string, cookieJar, error) {
+		return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
+	}
+
+	err := client.PopulatePlaylist(context.Background(), "_PLAYLIST_ID", 0, []string{"_TRACK_A"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestClient_PopulatePlaylist_chunks(t *testing.T) {
+	tracks := make([]string, 120)
+	for i := range tracks {
+		tracks[i] = fmt.Sprintf("_TRACK_%03d", i)
+	}
+
+	table := []struct {
+		name           string
+		failingChunk   int
+		expectedChunks int
+		expectedError  string
+		expectedAdded  int
+	}{
+		{
+			name:           "ok",
+			expectedChunks: 3,
+		},
+		{
+			name:           "first chunk error",
+			failingChunk:   1,
+			expectedChunks: 1,
+			expectedError:  "this song already exists in this playlist",
+		},
+		{
+			name:           "later chunk error",
+			failingChunk:   3,
+			expectedChunks: 3,
+			expectedError:  "100 tracks already added: this song already exists in this playlist",
+			expectedAdded:  100,
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			var received [][][]any
+
+			api := newFakeAPI(t)
+			api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+				var body struct {
+					Songs [][]any `json:"songs"`
+				}
+				assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+				received = append(received, body.Songs)
+
+				file := "test-data/populate_playlist_ok.json"
+				if len(received) == test.failingChunk {
+					file = "test-data/populate_playlist_error.json"
+				}
+
+				_, err := w.Write([]byte(tests.ReadFile(t, file)))
+				assert.NoError(t, err)
+			})
 
 			client := New(http.DefaultClient, "_ARL", logs.New(nil))
-			client.apiURL = svr.URL
+			client.apiURL = api.URL
 			client.tokenizer = func(context.Context) (string, cookieJar, error) {
 				return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
 			}
 
-			err := client.PopulatePlaylist(context.Background(), "_PLAYLIST_ID", []string{"_TRACK_A"})
+			err := client.PopulatePlaylist(context.Background(), "_PLAYLIST_ID", 30, tracks)
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			var perr *playlists.PopulateError
+			if test.expectedAdded != 0 {
+				require.ErrorAs(t, err, &perr)
+				assert.Equal(t, test.expectedAdded, perr.Added)
+			} else {
+				assert.False(t, errors.As(err, &perr))
+			}
+
+			require.Len(t, received, test.expectedChunks)
+
+			var position int
+			for _, chunk := range received {
+				assert.LessOrEqual(t, len(chunk), maxSongsPerRequest)
+				for _, song := range chunk {
+					assert.Equal(t, []any{tracks[position], float64(30 + position)}, song, "after the 30 tracks already there")
+					position++
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Playlists(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/?api_token=_TOKEN&api_version=1.0&method=deezer.pageProfile", req.URL.String())
+		assert.JSONEq(t, `{"USER_ID":123,"tab":"playlists","nb":-1}`, tests.ReadBody(t, req))
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/playlists_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := New(http.DefaultClient, "_ARL", logs.New(nil))
+	client.apiURL = api.URL
+	client.userID.Store(123)
+	client.tokenizer = func(context.Context) (string, cookieJar, error) {
+		return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
+	}
+
+	actual, err := client.Playlists(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []playlists.Playlist{
+		{ID: "11856839981", Name: "Friday party"},
+		{ID: "1313621735", Name: "Loved Tracks"},
+	}, actual)
+}
+
+func TestClient_PlaylistTracks(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/?api_token=_TOKEN&api_version=1.0&method=playlist.getSongs", req.URL.String())
+		assert.JSONEq(t, `{"playlist_id":"_PLAYLIST_ID","nb":-1}`, tests.ReadBody(t, req))
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/playlist_tracks_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := New(http.DefaultClient, "_ARL", logs.New(nil))
+	client.apiURL = api.URL
+	client.tokenizer = func(context.Context) (string, cookieJar, error) {
+		return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
+	}
+
+	actual, err := client.PlaylistTracks(context.Background(), "_PLAYLIST_ID")
+	require.NoError(t, err)
+
+	assert.Equal(t, []playlists.Track{{
+		ID:       "6623366",
+		Name:     "Porno For Pyros - Tahitian Moon [03:47] Good God's Urge",
+		Title:    "Tahitian Moon",
+		Artists:  []string{"Porno For Pyros"},
+		Album:    "Good God's Urge",
+		ISRC:     "USWB19500351",
+		Duration: 227 * time.Second,
+	}}, actual)
+}
+
+func TestClient_RemoveTracks(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/?api_token=_TOKEN&api_version=1.0&method=playlist.deleteSongs", req.URL.String())
+		assert.JSONEq(t, `{"playlist_id":"_PLAYLIST_ID","songs":[["_TRACK_A",0],["_TRACK_B",0]]}`, tests.ReadBody(t, req))
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/populate_playlist_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := New(http.DefaultClient, "_ARL", logs.New(nil))
+	client.apiURL = api.URL
+	client.tokenizer = func(context.Context) (string, cookieJar, error) {
+		return "_TOKEN", newJar(&http.Cookie{Name: "arl", Value: "_ARL"}), nil
+	}
+
+	err := client.RemoveTracks(context.Background(), "_PLAYLIST_ID", []string{"_TRACK_A", "_TRACK_B"})
+	require.NoError(t, err)
+}
+
+func TestClient_SearchISRC(t *testing.T) {
+	table := []struct {
+		name          string
+		responseBody  string
+		expected      []playlists.Track
+		expectedError string
+	}{
+		{
+			name:         "ok",
+			responseBody: tests.ReadFile(t, "test-data/search_isrc_ok.json"),
+			expected: []playlists.Track{{
+				ID:       "6623366",
+				Name:     "Porno For Pyros - Tahitian Moon [03:47] Good God's Urge",
+				Title:    "Tahitian Moon",
+				Artists:  []string{"Porno For Pyros"},
+				Album:    "Good God's Urge",
+				ISRC:     "USWB19500351",
+				Duration: 227 * time.Second,
+			}},
+		},
+		{
+			name:         "not found",
+			responseBody: tests.ReadFile(t, "test-data/search_isrc_not_found.json"),
+		},
+		{
+			name:          "error",
+			responseBody:  `{"error":{"type":"Exception","message":"Quota limit exceeded","code":4}}`,
+			expectedError: "quota limit exceeded",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.handle("GET /track/{isrc}", func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, http.MethodGet, req.Method)
+				assert.Equal(t, "/track/isrc:USWB19500351", req.URL.Path)
+
+				_, err := w.Write([]byte(test.responseBody))
+				assert.NoError(t, err)
+			})
+
+			client := New(http.DefaultClient, "_ARL", logs.New(nil))
+			client.publicURL = api.URL
+
+			matches, err := client.SearchISRC(context.Background(), "USWB19500351")
 			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			assert.Equal(t, test.expected, matches)
+		})
+	}
+}
+
+func Test_song_available(t *testing.T) {
+	table := []struct {
+		body     string
+		expected bool
+	}{
+		{body: `{}`, expected: true},
+		{body: `{"READABLE":true}`, expected: true},
+		{body: `{"READABLE":false}`, expected: false},
+		{body: `{"RIGHTS":{"STREAM_ADS_AVAILABLE":false,"STREAM_SUB_AVAILABLE":true}}`, expected: true},
+		{body: `{"RIGHTS":{"STREAM_ADS_AVAILABLE":false}}`, expected: false},
+		{body: `{"RIGHTS":[]}`, expected: false},
+	}
+	for _, test := range table {
+		t.Run(test.body, func(t *testing.T) {
+			var s song
+			require.NoError(t, json.Unmarshal([]byte(test.body), &s))
+
+			assert.Equal(t, test.expected, s.available())
+			assert.Equal(t, !test.expected, s.track(nil).Unavailable)
+		})
+	}
+}
+
+func TestClient_ValidID(t *testing.T) {
+	table := map[string]bool{
+		"3135556":                       true,
+		"":                              false,
+		"0":                             false,
+		"-1":                            false,
+		"spotify:track:4u7EnebtmKWzUH4": false,
+	}
+	for id, expected := range table {
+		t.Run(id, func(t *testing.T) {
+			assert.Equal(t, expected, New(nil, "", nil).ValidID(id))
 		})
 	}
 }
@@ -0,0 +1,36 @@
+package deezer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/agukrapo/playlist-creator/internal/logs"
+	"github.com/agukrapo/playlist-creator/internal/retry"
+	"github.com/agukrapo/playlist-creator/playlists"
+	"github.com/agukrapo/playlist-creator/playlists/playliststest"
+)
+
+func TestClient_conformance(t *testing.T) {
+	client := func(arl string, configure func(api *fakeAPI)) func(t *testing.T) playlists.Target {
+		return func(t *testing.T) playlists.Target {
+			api := newFakeAPI(t)
+			configure(api)
+
+			out := New(retry.New(http.DefaultClient, retry.Attempts(3)), arl, logs.New(nil))
+			out.apiURL = api.URL
+			out.publicURL = api.URL
+			return out
+		}
+	}
+
+	playliststest.Suite{
+		New:          client("_ARL", func(*fakeAPI) {}),
+		Unauthorized: client("_EXPIRED", func(*fakeAPI) {}),
+		Throttled:    client("_ARL", func(api *fakeAPI) { api.throttled = 2 }),
+		Failing:      client("_ARL", func(api *fakeAPI) { api.maxAdds = 1 }),
+		Query:        "porno for pyros",
+		ISRC:         "USWB19500351",
+		Fields:       playlists.Fields{Artist: "Queen", Title: "Bohemian Rhapsody"},
+		Tracks:       []string{"1", "2", "3"},
+	}.Run(t)
+}
@@ -0,0 +1,269 @@
+package deezer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI serves the gateway methods and the public API endpoint used by the Client from an in-memory catalog.
+// Tests replace single routes with handle to check the requests and reply with recorded responses.
+type fakeAPI struct {
+	*httptest.Server
+
+	routes *http.ServeMux // routes replaced by the test, served first
+	songs  []song
+
+	mu        sync.Mutex
+	playlists map[string]*fakePlaylist
+	order     []string
+	throttled int // requests to answer as rate limited
+	maxAdds   int // playlist.addSongs calls accepted before failing the rest, zero for no limit
+	adds      int
+}
+
+type fakePlaylist struct {
+	title string
+	songs []string
+}
+
+// fakeCatalog holds the songs served by fakeAPI.
+const fakeCatalog = `[
+	{"SNG_ID":"1","SNG_TITLE":"Tahitian Moon","ART_NAME":"Porno For Pyros","ALB_TITLE":"Good God's Urge","ISRC":"USWB19500351","DURATION":"227"},
+	{"SNG_ID":"2","SNG_TITLE":"Pets","ART_NAME":"Porno For Pyros","ALB_TITLE":"Porno For Pyros","ISRC":"USWB19300312","DURATION":"219"},
+	{"SNG_ID":"3","SNG_TITLE":"Bohemian Rhapsody","ART_NAME":"Queen","ALB_TITLE":"A Night at the Opera","ISRC":"GBUM71029604","DURATION":"355"}
+]`
+
+// errNoPlaylist is answered as a DATA_ERROR, like the gateway does for unknown IDs.
+var errNoPlaylist = errors.New("playlist::getData")
+
+// newFakeAPI starts a fakeAPI accepting the "_ARL" cookie, closed when the test ends.
+// The gateway is served at the root path and the public API under /track.
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{routes: http.NewServeMux(), playlists: make(map[string]*fakePlaylist)}
+	require.NoError(t, json.Unmarshal([]byte(fakeCatalog), &api.songs))
+
+	methods := map[string]func(in map[string]any) (any, error){
+		"deezer.pageSearch":    api.search,
+		"playlist.create":      api.createPlaylist,
+		"playlist.addSongs":    api.addSongs,
+		"playlist.deleteSongs": api.deleteSongs,
+		"playlist.getSongs":    api.playlistSongs,
+		"deezer.pageProfile":   api.profile,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /track/{isrc}", api.isrc)
+	mux.HandleFunc("POST /{$}", func(w http.ResponseWriter, req *http.Request) {
+		method := req.URL.Query().Get("method")
+
+		if method == "deezer.getUserData" {
+			var userID int
+			if cookie, err := req.Cookie("arl"); err == nil && cookie.Value == "_ARL" {
+				userID = 1
+			}
+			_, _ = fmt.Fprintf(w, `{"error":[],"results":{"USER":{"USER_ID":%d},"checkForm":"_TOKEN"}}`, userID)
+			return
+		}
+
+		fn, ok := methods[method]
+		if !ok || req.URL.Query().Get("api_token") != "_TOKEN" {
+			_, _ = w.Write([]byte(`{"error":{"VALID_TOKEN_REQUIRED":"Invalid CSRF token"},"results":{}}`))
+			return
+		}
+
+		var in map[string]any
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+
+		out, err := fn(in)
+		if err != nil {
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"DATA_ERROR": err.Error()}, "results": map[string]any{}})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": []any{}, "results": out})
+	})
+
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if h, pattern := api.routes.Handler(req); pattern != "" {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		if api.throttle() {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		mux.ServeHTTP(w, req)
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// handle replaces the route matching the pattern, "POST /{$}" being the gateway one.
+func (api *fakeAPI) handle(pattern string, handler http.HandlerFunc) {
+	api.routes.HandleFunc(pattern, handler)
+}
+
+func (api *fakeAPI) throttle() bool {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if api.throttled == 0 {
+		return false
+	}
+
+	api.throttled--
+	return true
+}
+
+// search matches the songs containing every word of the query.
+func (api *fakeAPI) search(in map[string]any) (any, error) {
+	query := strings.ToLower(fmt.Sprint(in["query"]))
+
+	out := []song{}
+	for _, s := range api.songs {
+		text := strings.ToLower(s.Artist + " " + s.Title + " " + s.AlbumTitle)
+
+		match := true
+		for _, w := range strings.Fields(query) {
+			match = match && strings.Contains(text, w)
+		}
+		if match {
+			out = append(out, s)
+		}
+	}
+
+	return map[string]any{"TRACK": map[string]any{"data": out}, "ALBUM": map[string]any{"data": []any{}}}, nil
+}
+
+func (api *fakeAPI) isrc(w http.ResponseWriter, req *http.Request) {
+	isrc := strings.TrimPrefix(req.PathValue("isrc"), "isrc:")
+
+	i := slices.IndexFunc(api.songs, func(s song) bool { return s.ISRC == isrc })
+	if i == -1 {
+		_, _ = w.Write([]byte(`{"error":{"type":"DataException","message":"no data","code":800}}`))
+		return
+	}
+
+	s := api.songs[i]
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":       json.Number(s.SongID),
+		"title":    s.Title,
+		"isrc":     s.ISRC,
+		"duration": json.Number(s.Duration),
+		"artist":   map[string]string{"name": s.Artist},
+		"album":    map[string]string{"title": s.AlbumTitle},
+	})
+}
+
+func (api *fakeAPI) createPlaylist(in map[string]any) (any, error) {
+	id := fmt.Sprint(len(api.order) + 1)
+	api.playlists[id] = &fakePlaylist{title: fmt.Sprint(in["title"])}
+	api.order = append(api.order, id)
+
+	return json.Number(id), nil
+}
+
+// playlist returns the playlist of the playlist_id parameter.
+func (api *fakeAPI) playlist(in map[string]any) (*fakePlaylist, error) {
+	p, ok := api.playlists[fmt.Sprint(in["playlist_id"])]
+	if !ok {
+		return nil, errNoPlaylist
+	}
+
+	return p, nil
+}
+
+// songIDs returns the IDs of the songs parameter, a list of [ID, position] pairs.
+func songIDs(in map[string]any) []string {
+	songs, _ := in["songs"].([]any)
+
+	out := make([]string, 0, len(songs))
+	for _, s := range songs {
+		pair, _ := s.([]any)
+		out = append(out, fmt.Sprint(pair[0]))
+	}
+
+	return out
+}
+
+// insertSongs places every song of the songs parameter at its position, as the gateway does.
+func insertSongs(p *fakePlaylist, in map[string]any) {
+	songs, _ := in["songs"].([]any)
+
+	for _, s := range songs {
+		pair, _ := s.([]any)
+		position, _ := pair[1].(float64)
+		p.songs = slices.Insert(p.songs, min(int(position), len(p.songs)), fmt.Sprint(pair[0]))
+	}
+}
+
+func (api *fakeAPI) addSongs(in map[string]any) (any, error) {
+	p, err := api.playlist(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if api.adds++; api.maxAdds != 0 && api.adds > api.maxAdds {
+		return false, nil
+	}
+
+	insertSongs(p, in)
+
+	return true, nil
+}
+
+func (api *fakeAPI) deleteSongs(in map[string]any) (any, error) {
+	p, err := api.playlist(in)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := songIDs(in)
+	p.songs = slices.DeleteFunc(p.songs, func(id string) bool {
+		return slices.Contains(removed, id)
+	})
+
+	return true, nil
+}
+
+func (api *fakeAPI) playlistSongs(in map[string]any) (any, error) {
+	p, err := api.playlist(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []song{}
+	for _, id := range p.songs {
+		out = append(out, api.songs[slices.IndexFunc(api.songs, func(s song) bool { return s.SongID == id })])
+	}
+
+	return map[string]any{"data": out}, nil
+}
+
+func (api *fakeAPI) profile(map[string]any) (any, error) {
+	data := make([]map[string]string, 0, len(api.order))
+	for _, id := range api.order {
+		data = append(data, map[string]string{"PLAYLIST_ID": id, "TITLE": api.playlists[id].title})
+	}
+
+	return map[string]any{"TAB": map[string]any{"playlists": map[string]any{"data": data}}}, nil
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/files"
+	"github.com/agukrapo/playlist-creator/playlists"
+)
+
+// DefaultTTL is how long the search results are kept by default.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// Target is a playlists.Target keeping its search results on disk, a file per target, kind of search and query.
+// Entries are written to a temporary file and renamed, so concurrent searches never read a partial entry.
+// Cache read and write failures are ignored, falling back to the decorated target.
+type Target struct {
+	playlists.Target
+
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// New decorates the target, caching its search results under dir for the given TTL.
+func New(target playlists.Target, dir string, ttl time.Duration) *Target {
+	return &Target{
+		Target: target,
+		dir:    filepath.Join(filepath.Clean(dir), target.Name()),
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Dir returns the default cache directory, placed under the user cache directory.
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "playlist-creator", "search"), nil
+}
+
+// Clear removes every cached search result under dir.
+func Clear(dir string) error {
+	return os.RemoveAll(filepath.Clean(dir))
+}
+
+func (t *Target) SearchTracks(ctx context.Context, query string) ([]playlists.Track, error) {
+	return t.cached("query", query, func() ([]playlists.Track, error) {
+		return t.Target.SearchTracks(ctx, query)
+	})
+}
+
+func (t *Target) SearchISRC(ctx context.Context, isrc string) ([]playlists.Track, error) {
+	return t.cached("isrc", isrc, func() ([]playlists.Track, error) {
+		return t.Target.SearchISRC(ctx, isrc)
+	})
+}
+
+func (t *Target) SearchFields(ctx context.Context, fields playlists.Fields) ([]playlists.Track, error) {
+	key, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.cached("fields", string(key), func() ([]playlists.Track, error) {
+		return t.Target.SearchFields(ctx, fields)
+	})
+}
+
+type entry struct {
+	Kind    string            `json:"kind"`
+	Key     string            `json:"key"`
+	Created time.Time         `json:"created"`
+	Tracks  []playlists.Track `json:"tracks"`
+}
+
+// cached returns the stored results of the search, or runs it and stores its results when missing or expired.
+func (t *Target) cached(kind, key string, search func() ([]playlists.Track, error)) ([]playlists.Track, error) {
+	path := t.path(kind, key)
+
+	if e, ok := t.read(path); ok && e.Kind == kind && e.Key == key {
+		return e.Tracks, nil
+	}
+
+	tracks, err := search()
+	if err != nil {
+		return nil, err
+	}
+
+	_ = t.write(path, entry{Kind: kind, Key: key, Created: t.now(), Tracks: tracks})
+
+	return tracks, nil
+}
+
+func (t *Target) path(kind, key string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + key))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *Target) read(path string) (entry, bool) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return entry{}, false
+	}
+
+	var out entry
+	if err := json.Unmarshal(bytes, &out); err != nil {
+		return entry{}, false
+	}
+
+	if t.now().Sub(out.Created) > t.ttl {
+		return entry{}, false
+	}
+
+	return out, true
+}
+
+func (t *Target) write(path string, e entry) error {
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return files.WriteFile(path, bytes, 0o600)
+}
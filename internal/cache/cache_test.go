@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/playlists"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingTarget struct {
+	playlists.Target
+
+	calls atomic.Int32
+	err   error
+}
+
+func (ct *countingTarget) Name() string {
+	return "counting"
+}
+
+func (ct *countingTarget) SearchTracks(_ context.Context, query string) ([]playlists.Track, error) {
+	ct.calls.Add(1)
+	if ct.err != nil {
+		return nil, ct.err
+	}
+	return []playlists.Track{{ID: "id:" + query, Name: query, Duration: time.Minute}}, nil
+}
+
+func (ct *countingTarget) SearchISRC(_ context.Context, isrc string) ([]playlists.Track, error) {
+	ct.calls.Add(1)
+	return []playlists.Track{{ID: "isrc:" + isrc}}, nil
+}
+
+func (ct *countingTarget) SearchFields(_ context.Context, fields playlists.Fields) ([]playlists.Track, error) {
+	ct.calls.Add(1)
+	return []playlists.Track{{ID: "fields:" + fields.Title}}, nil
+}
+
+func TestTarget(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	inner := &countingTarget{}
+	target := New(inner, dir, time.Hour)
+	target.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	first, err := target.SearchTracks(ctx, "query")
+	require.NoError(t, err)
+
+	reopened := New(inner, dir, time.Hour)
+	reopened.now = target.now
+
+	again, err := reopened.SearchTracks(ctx, "query")
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "cached on disk")
+	assert.Equal(t, int32(1), inner.calls.Load())
+
+	isrc, err := target.SearchISRC(ctx, "query")
+	require.NoError(t, err)
+	assert.Equal(t, "isrc:query", isrc[0].ID, "kinds of search don't mix")
+
+	fields, err := target.SearchFields(ctx, playlists.Fields{Title: "query"})
+	require.NoError(t, err)
+	assert.Equal(t, "fields:query", fields[0].ID)
+	assert.Equal(t, int32(3), inner.calls.Load())
+
+	now = now.Add(2 * time.Hour)
+	_, err = target.SearchTracks(ctx, "query")
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), inner.calls.Load(), "expired")
+
+	require.NoError(t, Clear(dir))
+	_, err = target.SearchTracks(ctx, "query")
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), inner.calls.Load(), "cleared")
+}
+
+func TestTarget_error(t *testing.T) {
+	inner := &countingTarget{err: errors.New("_error")}
+	target := New(inner, t.TempDir(), time.Hour)
+
+	for range 2 {
+		_, err := target.SearchTracks(context.Background(), "query")
+		require.EqualError(t, err, "_error")
+	}
+
+	assert.Equal(t, int32(2), inner.calls.Load(), "errors aren't cached")
+}
+
+func TestTarget_concurrent(t *testing.T) {
+	inner := &countingTarget{}
+	target := New(inner, t.TempDir(), time.Hour)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			query := fmt.Sprintf("query %d", i%5)
+			tracks, err := target.SearchTracks(context.Background(), query)
+			assert.NoError(t, err)
+			assert.Equal(t, []playlists.Track{{ID: "id:" + query, Name: query, Duration: time.Minute}}, tracks)
+		}()
+	}
+	wg.Wait()
+}
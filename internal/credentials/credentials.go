@@ -0,0 +1,129 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/agukrapo/playlist-creator/internal/env"
+	"github.com/agukrapo/playlist-creator/internal/files"
+)
+
+var ErrNotFound = errors.New("credential not found")
+
+// Backend stores secrets per target.
+type Backend interface {
+	Get(target, key string) (string, error)
+	Set(target, key, value string) error
+}
+
+// File is a Backend that keeps every secret in a single JSON file readable only by the current user.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFile creates a File backend kept at path, the file is created by the first Set.
+func NewFile(path string) *File {
+	return &File{path: filepath.Clean(path)}
+}
+
+// Default returns the File backend placed under the user config directory.
+func Default() (*File, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFile(filepath.Join(dir, "playlist-creator", "credentials.json")), nil
+}
+
+type secrets map[string]map[string]string
+
+// Get returns the secret stored for the target, or ErrNotFound when there is none.
+func (f *File) Get(target, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all, err := f.read()
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := all[target][key]
+	if !ok || v == "" {
+		return "", fmt.Errorf("%s %s: %w", target, key, ErrNotFound)
+	}
+
+	return v, nil
+}
+
+// Set stores the secret for the target, rewriting the file only when the value changes.
+func (f *File) Set(target, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	if all[target] == nil {
+		all[target] = make(map[string]string)
+	}
+
+	if all[target][key] == value {
+		return nil
+	}
+
+	all[target][key] = value
+
+	return f.write(all)
+}
+
+func (f *File) read() (secrets, error) {
+	bytes, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(secrets), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(secrets)
+	if err := json.Unmarshal(bytes, &out); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+
+	return out, nil
+}
+
+func (f *File) write(all secrets) error {
+	bytes, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return files.WriteFile(f.path, bytes, 0o600)
+}
+
+// Lookup returns the value of the environment variable, remembering it in the backend,
+// or the value stored by a previous run when the variable is not set.
+func Lookup(backend Backend, variable, target, key string) (string, error) {
+	if v, _ := env.Lookup[string](variable); v != "" {
+		if err := backend.Set(target, key, v); err != nil {
+			return "", err
+		}
+		return v, nil
+	}
+
+	v, err := backend.Get(target, key)
+	if err != nil {
+		return "", fmt.Errorf("environment variable %s not set: %w", variable, err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "credentials.json")
+
+	f := NewFile(path)
+
+	_, err := f.Get("deezer", "arl")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, f.Set("deezer", "arl", "_ARL"))
+	require.NoError(t, f.Set("spotify", "token", "_TOKEN"))
+	require.NoError(t, f.Set("deezer", "arl", "_ARL2"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	reopened := NewFile(path)
+
+	v, err := reopened.Get("deezer", "arl")
+	require.NoError(t, err)
+	assert.Equal(t, "_ARL2", v)
+
+	v, err = reopened.Get("spotify", "token")
+	require.NoError(t, err)
+	assert.Equal(t, "_TOKEN", v)
+
+	_, err = reopened.Get("spotify", "arl")
+	assert.EqualError(t, err, "spotify arl: credential not found")
+}
+
+func TestFile_corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err := NewFile(path).Get("deezer", "arl")
+	assert.Error(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "credentials.json"))
+
+	_, err := Lookup(f, "PLAYLIST_CREATOR_TEST_SECRET", "target", "key")
+	assert.EqualError(t, err, "environment variable PLAYLIST_CREATOR_TEST_SECRET not set: target key: credential not found")
+
+	t.Setenv("PLAYLIST_CREATOR_TEST_SECRET", "_SECRET")
+
+	v, err := Lookup(f, "PLAYLIST_CREATOR_TEST_SECRET", "target", "key")
+	require.NoError(t, err)
+	assert.Equal(t, "_SECRET", v)
+
+	stored, err := f.Get("target", "key")
+	require.NoError(t, err)
+	assert.Equal(t, "_SECRET", stored)
+}
@@ -0,0 +1,47 @@
+// Package files writes files atomically, so readers never see a partial one.
+package files
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes the data to a temporary file next to path and renames it, creating the directory if needed.
+// The directory is only accessible by the current user, the file gets the given permissions.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if err := write(tmp, data, perm); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+func write(f *os.File, data []byte, perm os.FileMode) error {
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
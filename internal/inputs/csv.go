@@ -0,0 +1,84 @@
+package inputs
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+)
+
+// CSV reads an item per row, the header row names the columns. Unknown columns are ignored.
+func CSV(r io.Reader) ([]results.Item, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	columns := make(map[field]int, len(header))
+	for i, h := range header {
+		if f, ok := lookupField(h); ok {
+			if _, dup := columns[f]; !dup {
+				columns[f] = i
+			}
+		}
+	}
+
+	if _, ok := columns[title]; !ok {
+		if _, ok := columns[query]; !ok {
+			return nil, &ParseError{Line: 1, Err: fmt.Errorf("missing %s or %s column", title, query)}
+		}
+	}
+
+	var out []results.Item
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		rec := make(record, len(columns))
+		for f, i := range columns {
+			if i < len(row) {
+				rec[f] = row[i]
+			}
+		}
+
+		item, err := rec.item()
+		if err != nil {
+			line, column := reader.FieldPos(0)
+
+			var fe *fieldError
+			if errors.As(err, &fe) {
+				line, column = reader.FieldPos(columns[fe.field])
+			} else {
+				column = 0
+			}
+
+			return nil, &ParseError{Line: line, Column: column, Err: err}
+		}
+
+		out = append(out, item)
+	}
+
+	return out, nil
+}
+
+func csvError(err error) error {
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return &ParseError{Line: pe.Line, Column: pe.Column, Err: pe.Err}
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+package inputs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+)
+
+// Reader reads the items of an input file.
+type Reader func(r io.Reader) ([]results.Item, error)
+
+var readers = map[string]Reader{
+	"text": Text,
+	"csv":  CSV,
+	"json": JSON,
+	"m3u":  M3U,
+	"m3u8": M3U,
+	"pls":  PLS,
+	"lock": results.ReadLock,
+}
+
+// ParseError locates an invalid input entry, Column is zero when the whole entry is invalid.
+type ParseError struct {
+	Line, Column int
+	Err          error
+}
+
+func (pe *ParseError) Error() string {
+	if pe.Column == 0 {
+		return fmt.Sprintf("line %d: %v", pe.Line, pe.Err)
+	}
+	return fmt.Sprintf("line %d, column %d: %v", pe.Line, pe.Column, pe.Err)
+}
+
+func (pe *ParseError) Unwrap() error {
+	return pe.Err
+}
+
+// SkippedError reports the entries without a track left out of an input, returned along with the items of the others.
+type SkippedError struct {
+	Lines []int // of the skipped entries
+}
+
+func (se *SkippedError) Error() string {
+	return fmt.Sprintf("skipped %d entries missing %s or %s, the first at line %d", len(se.Lines), title, query, se.Lines[0])
+}
+
+// Format returns the reader of the given format, or the one matching the file extension when format is empty.
+// Unknown extensions are read as text.
+func Format(format, path string) (Reader, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if _, ok := readers[format]; !ok {
+			format = "text"
+		}
+	}
+
+	r, ok := readers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown input format %s", format)
+	}
+
+	return r, nil
+}
+
+// Open reads the items of the file at path using the given format, see Format.
+// A *SkippedError is returned along with the items read when some entries were left out.
+func Open(path, format string) ([]results.Item, error) {
+	read, err := Format(format, path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	out, err := read(file)
+	if err != nil {
+		return out, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return out, nil
+}
+
+// Text reads an item per line, see results.ParseItem. Blank lines are skipped.
+func Text(r io.Reader) ([]results.Item, error) {
+	var out []results.Item
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			out = append(out, results.ParseItem(line))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+type field string
+
+const (
+	artist     field = "artist"
+	title      field = "title"
+	album      field = "album"
+	isrc       field = "isrc"
+	duration   field = "duration"
+	durationMS field = "duration_ms"
+	query      field = "query"
+	id         field = "id"
+	name       field = "name"
+)
+
+// aliases maps normalized column and key names, as found in spreadsheet and streaming history exports, to fields.
+var aliases = map[string]field{
+	"artist":                        artist,
+	"artists":                       artist,
+	"artistname":                    artist,
+	"artistnames":                   artist,
+	"mastermetadataalbumartistname": artist,
+	"title":                         title,
+	"track":                         title,
+	"trackname":                     title,
+	"song":                          title,
+	"songname":                      title,
+	"mastermetadatatrackname":       title,
+	"album":                         album,
+	"albumname":                     album,
+	"mastermetadataalbumalbumname":  album,
+	"spotifytrackuri":               id,
+	"isrc":                          isrc,
+	"duration":                      duration,
+	"length":                        duration,
+	"durationms":                    durationMS,
+	"trackdurationms":               durationMS,
+	"query":                         query,
+	"id":                            id,
+	"name":                          name,
+}
+
+func lookupField(key string) (field, bool) {
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, key)
+
+	f, ok := aliases[normalized]
+	return f, ok
+}
+
+// fieldError is an error caused by a single field value.
+type fieldError struct {
+	field field
+	err   error
+}
+
+func (fe *fieldError) Error() string {
+	return fmt.Sprintf("%s: %v", fe.field, fe.err)
+}
+
+// errNoTrack is returned for entries naming no track, like the podcast ones of a streaming history.
+var errNoTrack = fmt.Errorf("missing %s or %s", title, query)
+
+// record holds the field values of an entry.
+type record map[field]string
+
+func (r record) item() (results.Item, error) {
+	for f, v := range r {
+		r[f] = strings.TrimSpace(v)
+	}
+
+	if r[title] == "" && r[query] == "" {
+		return results.Item{}, errNoTrack
+	}
+
+	out := results.NewItem(r[query]).WithFields(r[artist], r[title], r[album])
+
+	if v := r[isrc]; v != "" {
+		out = out.WithISRC(strings.ToUpper(v))
+	}
+
+	if v := r[duration]; v != "" {
+		d := results.ParseDuration(v)
+		if d == 0 {
+			return results.Item{}, &fieldError{field: duration, err: fmt.Errorf("invalid value %q", v)}
+		}
+		out = out.WithDuration(d)
+	}
+
+	if v := r[durationMS]; v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms <= 0 {
+			return results.Item{}, &fieldError{field: durationMS, err: fmt.Errorf("invalid value %q", v)}
+		}
+		out = out.WithDuration(time.Duration(ms) * time.Millisecond)
+	}
+
+	if v := r[id]; v != "" {
+		n := r[name]
+		if n == "" {
+			n = out.Query()
+		}
+		out = out.WithID(v).WithName(n).WithActive(true)
+	}
+
+	return out, nil
+}
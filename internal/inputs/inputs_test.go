@@ -0,0 +1,201 @@
+package inputs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	table := []struct {
+		path     string
+		expected []results.Item
+	}{
+		{
+			path: "test-data/songs.csv",
+			expected: []results.Item{
+				results.NewItem("").WithFields("Queen", "Bohemian Rhapsody", "A Night at the Opera").
+					WithISRC("GBUM71029604").WithDuration(354320 * time.Millisecond),
+				results.NewItem("").WithFields("The Clash", "Mustapha Dance", ""),
+			},
+		},
+		{
+			path: "test-data/songs.json",
+			expected: []results.Item{
+				results.NewItem("").WithFields("Queen", "Bohemian Rhapsody", ""),
+				results.NewItem("").WithFields("The Clash", "Mustapha Dance", ""),
+				results.NewItem("porno for pyros tahitian moon").WithFields("", "", "").
+					WithID("123").WithName("Tahitian Moon").WithActive(true),
+			},
+		},
+		{
+			path: "test-data/songs.m3u8",
+			expected: []results.Item{
+				results.NewItem("Queen - Bohemian Rhapsody").WithFields("Queen", "Bohemian Rhapsody", "").WithDuration(354 * time.Second),
+				results.NewItem("Tahitian Moon").WithFields("", "", ""),
+				results.NewItem("The Clash - Mustapha Dance").WithFields("The Clash", "Mustapha Dance", ""),
+				results.NewItem("Nirvana - Lithium").WithFields("Nirvana", "Lithium", "Nevermind"),
+			},
+		},
+		{
+			path: "test-data/songs.pls",
+			expected: []results.Item{
+				results.NewItem("Queen - Bohemian Rhapsody").WithFields("Queen", "Bohemian Rhapsody", "").WithDuration(354 * time.Second),
+				results.NewItem("Porno For Pyros - Tahitian Moon").WithFields("Porno For Pyros", "Tahitian Moon", ""),
+			},
+		},
+	}
+	for _, test := range table {
+		t.Run(test.path, func(t *testing.T) {
+			actual, err := Open(test.path, "")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	table := []struct {
+		format, path  string
+		input         string
+		expected      []string
+		expectedError string
+	}{
+		{path: "songs.txt", input: "a\n\n b \n", expected: []string{"a", "b"}},
+		{path: "songs", input: "a,b\n", expected: []string{"a,b"}},
+		{path: "songs.CSV", input: "title,artist\nb,a\n", expected: []string{"a b"}},
+		{format: "csv", path: "songs.txt", input: "query\na\n", expected: []string{"a"}},
+		{format: "json", path: "songs.csv", input: `[{"title":"b"}]`, expected: []string{"b"}},
+		{format: "xml", path: "songs.xml", expectedError: "unknown input format xml"},
+	}
+	for _, test := range table {
+		t.Run(test.format+" "+test.path, func(t *testing.T) {
+			read, err := Format(test.format, test.path)
+			require.Equal(t, test.expectedError, tests.AsString(err))
+			if err != nil {
+				return
+			}
+
+			items, err := read(strings.NewReader(test.input))
+			require.NoError(t, err)
+
+			actual := make([]string, 0, len(items))
+			for _, item := range items {
+				actual = append(actual, item.Query())
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestCSV_errors(t *testing.T) {
+	table := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "missing column", input: "artist,album\na,b\n", expected: "line 1: missing title or query column"},
+		{name: "missing title", input: "artist,title\na,b\nc,\n", expected: "line 3: missing title or query"},
+		{name: "blank title", input: "artist,title\na,\"  \"\n", expected: "line 2: missing title or query"},
+		{name: "invalid duration", input: "title,duration\na,3:45\nb,abc\n", expected: "line 3, column 3: duration: invalid value \"abc\""},
+		{name: "bare quote", input: "title\na\"b\n", expected: "line 2, column 2: bare \" in non-quoted-field"},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := CSV(strings.NewReader(test.input))
+			assert.Equal(t, test.expected, tests.AsString(err))
+		})
+	}
+}
+
+func TestJSON_errors(t *testing.T) {
+	table := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "not an array", input: `{"title":"a"}`, expected: "line 1, column 1: expected an array"},
+		{name: "invalid value", input: "[\n  {\"title\":[\"a\"]}\n]", expected: "line 2, column 3: title: unexpected []interface {} value"},
+		{name: "syntax", input: "[\n  {\"title\" \"a\"}\n]", expected: "line 2, column 13: invalid character '\"' after object key"},
+		{name: "truncated", input: "[\n  {\"title\":\"a\"}", expected: "line 2, column 16: unexpected end of JSON input"},
+		{name: "empty", input: "", expected: "line 1, column 1: unexpected end of input"},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := JSON(strings.NewReader(test.input))
+			assert.Equal(t, test.expected, tests.AsString(err))
+		})
+	}
+}
+
+func TestJSON_skipped(t *testing.T) {
+	table := []struct {
+		name          string
+		input         string
+		expected      []string
+		expectedLines []int
+	}{
+		{name: "missing title", input: "[\n  {\"title\":\"a\"},\n  {\"artist\":\"b\"}\n]", expected: []string{"a"}, expectedLines: []int{3}},
+		{name: "blank title", input: `[{"title":" "}]`, expectedLines: []int{1}},
+		{name: "blank query", input: `[{"query":"\t","artist":"b"}]`, expectedLines: []int{1}},
+		{name: "null title", input: "[\n  {\"title\":null},\n  {\"title\":\"a\"},\n  {}\n]", expected: []string{"a"}, expectedLines: []int{2, 4}},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			items, err := JSON(strings.NewReader(test.input))
+
+			var serr *SkippedError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, test.expectedLines, serr.Lines)
+
+			var actual []string
+			for _, item := range items {
+				actual = append(actual, item.Query())
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestOpen_streamingHistory(t *testing.T) {
+	items, err := Open("test-data/streaming_history.json", "")
+	require.EqualError(t, err, "test-data/streaming_history.json: skipped 2 entries missing title or query, the first at line 16")
+
+	var serr *SkippedError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, []int{16, 30}, serr.Lines)
+
+	assert.Equal(t, []results.Item{
+		results.NewItem("").WithFields("Queen", "Bohemian Rhapsody", "A Night at the Opera").
+			WithID("spotify:track:4u7EnebtmKWzUH433cf5Qv").WithName("Queen Bohemian Rhapsody").WithActive(true),
+		results.NewItem("").WithFields("The Clash", "Mustapha Dance", "Sandinista!").
+			WithID("spotify:track:0ozF2TZgBsG7sR4GF7iAW3").WithName("The Clash Mustapha Dance").WithActive(true),
+	}, items)
+}
+
+func TestPlaylists_errors(t *testing.T) {
+	table := []struct {
+		name     string
+		read     Reader
+		input    string
+		expected string
+	}{
+		{name: "m3u duration", read: M3U, input: "#EXTM3U\n#EXTINF:abc,Title\na.mp3\n", expected: "line 2, column 9: invalid #EXTINF duration"},
+		{name: "m3u dangling", read: M3U, input: "#EXTM3U\n#EXTINF:10,Title\n", expected: "line 2: #EXTINF without entry"},
+		{name: "pls line", read: PLS, input: "[playlist]\nFile1\n", expected: "line 2: expected key=value"},
+		{name: "pls index", read: PLS, input: "[playlist]\nFile=a.mp3\n", expected: "line 2: invalid file index"},
+		{name: "pls length", read: PLS, input: "[playlist]\nFile1=a.mp3\nLength1=abc\n", expected: "line 3, column 9: invalid length"},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.read(strings.NewReader(test.input))
+			assert.Equal(t, test.expected, tests.AsString(err))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package inputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+)
+
+// JSON reads an item per object of a top level array. Unknown keys are ignored.
+// Objects without title or query, like the podcast and unavailable track entries of a streaming history, are skipped
+// and reported by a *SkippedError returned along with the items.
+func JSON(r io.Reader) ([]results.Item, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if tok, err := dec.Token(); err != nil {
+		return nil, jsonError(data, dec.InputOffset(), err)
+	} else if tok != json.Delim('[') {
+		return nil, positioned(data, 0, errors.New("expected an array"))
+	}
+
+	var out []results.Item
+	var skipped []int
+	for dec.More() {
+		offset := dec.InputOffset()
+
+		var entry map[string]any
+		if err := dec.Decode(&entry); err != nil {
+			return nil, jsonError(data, offset, err)
+		}
+
+		rec := make(record, len(entry))
+		for k, v := range entry {
+			f, ok := lookupField(k)
+			if !ok || v == nil {
+				continue
+			}
+
+			switch v := v.(type) {
+			case string:
+				rec[f] = v
+			case float64:
+				rec[f] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				return nil, positioned(data, offset, &fieldError{field: f, err: fmt.Errorf("unexpected %T value", v)})
+			}
+		}
+
+		item, err := rec.item()
+		if errors.Is(err, errNoTrack) {
+			line, _ := locate(data, offset)
+			skipped = append(skipped, line)
+			continue
+		}
+		if err != nil {
+			return nil, positioned(data, offset, err)
+		}
+
+		out = append(out, item)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, jsonError(data, dec.InputOffset(), err)
+	}
+
+	if len(skipped) != 0 {
+		return out, &SkippedError{Lines: skipped}
+	}
+
+	return out, nil
+}
+
+func jsonError(data []byte, offset int64, err error) error {
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		offset = se.Offset
+	}
+
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		offset = te.Offset
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		offset = int64(len(data))
+		err = errors.New("unexpected end of input")
+	}
+
+	return positioned(data, offset, err)
+}
+
+// positioned locates the offset as a ParseError.
+func positioned(data []byte, offset int64, err error) error {
+	line, column := locate(data, offset)
+
+	return &ParseError{Line: line, Column: column, Err: err}
+}
+
+// locate returns the line and column of the offset, skipping leading white space.
+func locate(data []byte, offset int64) (line, column int) {
+	offset = min(offset, int64(len(data)))
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) != -1 {
+		offset++
+	}
+
+	line = 1 + bytes.Count(data[:offset], []byte("\n"))
+	column = int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+
+	return line, column
+}
@@ -0,0 +1,138 @@
+package inputs
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+)
+
+// M3U reads an item per entry of a M3U or M3U8 playlist, from its #EXTINF title or else from its file name.
+func M3U(r io.Reader) ([]results.Item, error) {
+	var (
+		out    []results.Item
+		info   *entry
+		album  string
+		artist string
+		n      int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n++
+
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			e, err := parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+			if err != nil {
+				return nil, &ParseError{Line: n, Column: len("#EXTINF:") + 1, Err: err}
+			}
+			info = e
+		case strings.HasPrefix(line, "#EXTALB:"):
+			album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+		case strings.HasPrefix(line, "#EXTART:"):
+			artist = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+		case strings.HasPrefix(line, "#"):
+		default:
+			e := entry{path: line, album: album}
+			if info != nil {
+				e.title, e.duration = info.title, info.duration
+			}
+			if e.title == "" && artist != "" {
+				e.title = artist + " - " + fileTitle(line)
+			}
+			out = append(out, e.item())
+			info = nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if info != nil {
+		return nil, &ParseError{Line: n, Err: errors.New("#EXTINF without entry")}
+	}
+
+	return out, nil
+}
+
+// parseExtInf reads the duration and title of "#EXTINF:354 key="value",Artist - Title", without the directive.
+func parseExtInf(in string) (*entry, error) {
+	head, title, _ := strings.Cut(in, ",")
+
+	seconds, _, _ := strings.Cut(strings.TrimSpace(head), " ")
+	v, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return nil, errors.New("invalid #EXTINF duration")
+	}
+
+	out := &entry{title: strings.TrimSpace(title)}
+	if v > 0 {
+		out.duration = time.Duration(v * float64(time.Second))
+	}
+
+	return out, nil
+}
+
+// entry is a playlist file entry.
+type entry struct {
+	path     string
+	title    string
+	album    string
+	duration time.Duration
+}
+
+func (e entry) item() results.Item {
+	text := e.title
+	if text == "" {
+		text = fileTitle(e.path)
+	}
+	if text == "" {
+		text = e.path
+	}
+
+	var out results.Item
+	if artist, title, ok := strings.Cut(text, " - "); ok {
+		out = results.NewItem(text).WithFields(strings.TrimSpace(artist), strings.TrimSpace(title), e.album)
+	} else {
+		out = results.NewItem(text).WithFields("", "", e.album)
+	}
+
+	return out.WithDuration(e.duration)
+}
+
+var trackNumber = regexp.MustCompile(`^\d{1,3}(?:[\s._-]+|$)`)
+
+// fileTitle derives a title from a path or URL file name, without extension and leading track number.
+func fileTitle(path string) string {
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		path = u.Path
+	} else if v, err := url.PathUnescape(path); err == nil {
+		path = v
+	}
+
+	name := path[strings.LastIndexAny(path, `/\`)+1:]
+	if i := strings.LastIndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+
+	name = strings.ReplaceAll(name, "_", " ")
+	if v := trackNumber.ReplaceAllString(name, ""); v != "" {
+		name = v
+	}
+
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,93 @@
+package inputs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+)
+
+// PLS reads an item per entry of a PLS playlist, from its title or else from its file name.
+func PLS(r io.Reader) ([]results.Item, error) {
+	entries := make(map[int]*entry)
+
+	var n int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n++
+
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, &ParseError{Line: n, Err: errors.New("expected key=value")}
+		}
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+
+		key := strings.TrimRight(k, "0123456789")
+		if key != "file" && key != "title" && key != "length" {
+			continue
+		}
+
+		i, err := strconv.Atoi(k[len(key):])
+		if err != nil {
+			return nil, &ParseError{Line: n, Err: fmt.Errorf("invalid %s index", key)}
+		}
+
+		e, ok := entries[i]
+		if !ok {
+			e = &entry{}
+			entries[i] = e
+		}
+
+		switch key {
+		case "file":
+			e.path = v
+		case "title":
+			e.title = v
+		case "length":
+			seconds, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, &ParseError{Line: n, Column: strings.Index(scanner.Text(), "=") + 2, Err: errors.New("invalid length")}
+			}
+			if seconds > 0 {
+				e.duration = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0, len(entries))
+	for i := range entries {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+
+	out := make([]results.Item, 0, len(entries))
+	for _, i := range indexes {
+		e := entries[i]
+		if e.path == "" && e.title == "" {
+			continue
+		}
+		out = append(out, e.item())
+	}
+
+	return out, nil
+}
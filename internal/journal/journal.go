@@ -0,0 +1,75 @@
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/agukrapo/playlist-creator/internal/files"
+	"github.com/agukrapo/playlist-creator/playlists"
+)
+
+// File is a playlists.Journal kept in a JSON file.
+type File struct {
+	path string
+}
+
+func New(path string) *File {
+	return &File{path: filepath.Clean(path)}
+}
+
+// Default returns the journal of the pushes from the given source, a file or playlist, to the target.
+// It is placed under the user cache directory.
+func Default(target, source string) (*File, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	file := target + "-" + hex.EncodeToString(sum[:8]) + ".json"
+
+	return New(filepath.Join(dir, "playlist-creator", "journal", file)), nil
+}
+
+func (f *File) Load() (playlists.Progress, bool, error) {
+	bytes, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return playlists.Progress{}, false, nil
+	}
+	if err != nil {
+		return playlists.Progress{}, false, err
+	}
+
+	var out playlists.Progress
+	if err := json.Unmarshal(bytes, &out); err != nil {
+		return playlists.Progress{}, false, err
+	}
+
+	return out, true, nil
+}
+
+func (f *File) Save(p playlists.Progress) error {
+	bytes, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return files.WriteFile(f.path, bytes, 0o600)
+}
+
+func (f *File) Done() error {
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Path returns the journal file path.
+func (f *File) Path() string {
+	return f.path
+}
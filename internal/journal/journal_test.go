@@ -0,0 +1,40 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agukrapo/playlist-creator/playlists"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "journal.json")
+
+	f := New(path)
+
+	_, ok, err := f.Load()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	progress := playlists.Progress{Target: "spotify", Name: "_NAME", PlaylistID: "_ID", Tracks: []string{"a", "b"}, Added: 1}
+	require.NoError(t, f.Save(progress))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	actual, ok, err := New(path).Load()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, progress, actual)
+
+	require.NoError(t, f.Done())
+	require.NoError(t, f.Done())
+
+	_, ok, err = f.Load()
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,173 @@
+package results
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LockVersion is the version of the lock files written by WriteLock.
+const LockVersion = 1
+
+type lockHeader struct {
+	Version int `json:"version"`
+}
+
+// lockEntry is a lock file line, entries without ID are unresolved.
+type lockEntry struct {
+	Target     string `json:"target,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Query      string `json:"query"`
+	Artist     string `json:"artist,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Album      string `json:"album,omitempty"`
+	ISRC       string `json:"isrc,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// WriteLock writes the items as JSON lines after a version header line. Active items keep their target, ID and name.
+func WriteLock(w io.Writer, items []Item) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(lockHeader{Version: LockVersion}); err != nil {
+		return err
+	}
+
+	for _, i := range items {
+		e := lockEntry{
+			Query:      i.text(),
+			Artist:     i.artist,
+			Title:      i.title,
+			Album:      i.album,
+			ISRC:       i.isrc,
+			DurationMS: i.duration.Milliseconds(),
+		}
+
+		if i.active {
+			e.Target, e.ID, e.Name = i.target, i.id, i.name
+		}
+
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadLock reads a lock file written by WriteLock, or a legacy one with a ParseItem line per item.
+func ReadLock(r io.Reader) ([]Item, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return readLegacyLock(data)
+	}
+
+	var (
+		out    []Item
+		header bool
+		n      int
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		n++
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if !header {
+			if err := readLockHeader(line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			header = true
+			continue
+		}
+
+		item, err := readLockEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		out = append(out, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func readLockHeader(line []byte) error {
+	var h lockHeader
+	if err := json.Unmarshal(line, &h); err != nil {
+		return err
+	}
+
+	switch {
+	case h.Version == 0:
+		return errors.New("missing lock version")
+	case h.Version > LockVersion:
+		return fmt.Errorf("unsupported lock version %d", h.Version)
+	}
+
+	return nil
+}
+
+func readLockEntry(line []byte) (Item, error) {
+	var e lockEntry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return Item{}, err
+	}
+
+	if strings.TrimSpace(e.Query) == "" {
+		return Item{}, errors.New("missing query")
+	}
+
+	out := ParseItem(e.Query)
+	out.artist, out.title, out.album = e.Artist, e.Title, e.Album
+	out.isrc = e.ISRC
+	out.duration = time.Duration(e.DurationMS) * time.Millisecond
+
+	if e.ID == "" {
+		out.unresolved = true
+		return out, nil
+	}
+
+	if e.Target == "" {
+		return Item{}, errors.New("missing target")
+	}
+
+	out.id, out.target, out.name, out.active = e.ID, e.Target, e.Name, true
+	if out.name == "" {
+		out.name = out.query
+	}
+
+	return out, nil
+}
+
+func readLegacyLock(data []byte) ([]Item, error) {
+	var out []Item
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, ParseItem(line))
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package results
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteLock(t *testing.T) {
+	items := []Item{
+		ParseItem("Queen - Bohemian Rhapsody (5:55)").WithID("_id").WithName("_name").WithTarget("spotify").WithActive(true),
+		NewItem("not found").WithTarget("spotify").WithID("_stale"),
+		ParseItem("artist=The Clash; title=Mustapha Dance; isrc=GBARL0600786"),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteLock(&buf, items))
+
+	expected := `{"version":1}
+{"target":"spotify","id":"_id","name":"_name","query":"Queen - Bohemian Rhapsody (5:55)","artist":"Queen","title":"Bohemian Rhapsody","duration_ms":355000}
+{"query":"not found"}
+{"query":"artist=The Clash; title=Mustapha Dance; isrc=GBARL0600786","artist":"The Clash","title":"Mustapha Dance","isrc":"GBARL0600786"}
+`
+	assert.Equal(t, expected, buf.String())
+
+	actual, err := ReadLock(&buf)
+	require.NoError(t, err)
+
+	unresolved := items[2]
+	unresolved.unresolved = true
+
+	assert.Equal(t, []Item{items[0], {query: "not found", unresolved: true}, unresolved}, actual)
+}
+
+func TestReadLock(t *testing.T) {
+	table := []struct {
+		name          string
+		input         string
+		expected      []Item
+		expectedError string
+	}{
+		{
+			name:  "legacy",
+			input: ">>LOCKED§_id§_name§_query\n\n>>UNRESOLVED§_other\n",
+			expected: []Item{
+				{query: "_query", id: "_id", name: "_name", active: true},
+				{query: "_other", unresolved: true},
+			},
+		},
+		{
+			name:  "default name",
+			input: "{\"version\":1}\n{\"target\":\"deezer\",\"id\":\"1\",\"query\":\"_query\",\"duration_ms\":1500}\n",
+			expected: []Item{
+				{query: "_query", id: "1", name: "_query", target: "deezer", duration: 1500 * time.Millisecond, active: true},
+			},
+		},
+		{
+			name:          "missing version",
+			input:         "{}\n",
+			expectedError: "line 1: missing lock version",
+		},
+		{
+			name:          "newer version",
+			input:         "{\"version\":2}\n",
+			expectedError: "line 1: unsupported lock version 2",
+		},
+		{
+			name:          "missing target",
+			input:         "{\"version\":1}\n{\"id\":\"1\",\"query\":\"_query\"}\n",
+			expectedError: "line 2: missing target",
+		},
+		{
+			name:          "missing query",
+			input:         "{\"version\":1}\n\n{\"target\":\"deezer\",\"id\":\"1\"}\n",
+			expectedError: "line 3: missing query",
+		},
+		{
+			name:          "invalid json",
+			input:         "{\"version\":1}\n{\"query\":\n",
+			expectedError: "line 2: unexpected end of JSON input",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ReadLock(strings.NewReader(test.input))
+			require.Equal(t, test.expectedError, tests.AsString(err))
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package results
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	fieldsLine    = regexp.MustCompile(`^\s*(artist|title|album|duration|isrc)\s*=`)
+	trailingAlbum = regexp.MustCompile(`\s*\[([^\[\]]+)\]\s*$`)
+	trailingTime  = regexp.MustCompile(`\s*\((\d{1,2}(?::\d{2}){1,2})\)\s*$`)
+)
+
+// ParseItem parses an input line, supported forms are:
+//
+//	free text query
+//	artist - title [album] (3:45)
+//	artist=Queen; title=Bohemian Rhapsody; album=A Night at the Opera; duration=5:55; isrc=GBUM71029604
+//	>>LOCKED§id§name§line
+//	>>UNRESOLVED§line
+//
+// album and duration are optional in the dash form. Free text and dash lines are searched as written, but for
+// a trailing album or duration, see Item.SearchQuery, and their fields narrow the search when the target supports it.
+func ParseItem(in string) Item {
+	if rest, ok := strings.CutPrefix(in, unresolved+"§"); ok {
+		out := ParseItem(rest)
+		out.unresolved = !out.active
+		return out
+	}
+
+	if chunks := strings.Split(in, "§"); len(chunks) == 4 {
+		out := ParseItem(chunks[3])
+		out.id = chunks[1]
+		out.name = chunks[2]
+		out.active = true
+		return out
+	}
+
+	if fieldsLine.MatchString(in) {
+		return parseFields(in)
+	}
+
+	return parseDashed(in)
+}
+
+func parseFields(in string) Item {
+	out := Item{line: in}
+
+	for _, field := range strings.Split(in, ";") {
+		k, v, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "artist":
+			out.artist = v
+		case "title":
+			out.title = v
+		case "album":
+			out.album = v
+		case "duration":
+			out.duration = ParseDuration(v)
+		case "isrc":
+			out.isrc = strings.ToUpper(v)
+		}
+	}
+
+	out.query = strings.TrimSpace(out.artist + " " + out.title)
+	if out.query == "" {
+		out.query = in
+	}
+
+	if out.query == in {
+		out.line = ""
+	}
+
+	return out
+}
+
+func parseDashed(in string) Item {
+	out := Item{query: in}
+
+	rest, album, duration := trimSuffixes(in)
+	out.album, out.duration = album, duration
+
+	artist, title, ok := strings.Cut(rest, " - ")
+	if !ok {
+		return Item{query: in}
+	}
+
+	out.artist, out.title = strings.TrimSpace(artist), strings.TrimSpace(title)
+	if out.artist == "" || out.title == "" {
+		return Item{query: in}
+	}
+
+	return out
+}
+
+// trimSuffixes cuts the trailing [album] and (duration) off the line, in either order.
+func trimSuffixes(in string) (rest, album string, duration time.Duration) {
+	rest = in
+	for range 2 {
+		if m := trailingTime.FindStringSubmatch(rest); m != nil && duration == 0 {
+			duration = ParseDuration(m[1])
+			rest = rest[:len(rest)-len(m[0])]
+		} else if m := trailingAlbum.FindStringSubmatch(rest); m != nil && album == "" {
+			album = strings.TrimSpace(m[1])
+			rest = rest[:len(rest)-len(m[0])]
+		}
+	}
+
+	return rest, album, duration
+}
+
+// ParseDuration reads seconds, m:ss or h:mm:ss durations, returns zero when invalid.
+func ParseDuration(in string) time.Duration {
+	var out time.Duration
+
+	for _, chunk := range strings.Split(in, ":") {
+		v, err := strconv.Atoi(strings.TrimSpace(chunk))
+		if err != nil || v < 0 {
+			return 0
+		}
+		out = out*60 + time.Duration(v)
+	}
+
+	return out * time.Second
+}
@@ -0,0 +1,114 @@
+package results
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseItem(t *testing.T) {
+	table := []struct {
+		in       string
+		expected Item
+	}{
+		{
+			in:       "porno for pyros tahitian moon",
+			expected: Item{query: "porno for pyros tahitian moon"},
+		},
+		{
+			in:       "Porno For Pyros - Tahitian Moon",
+			expected: Item{query: "Porno For Pyros - Tahitian Moon", artist: "Porno For Pyros", title: "Tahitian Moon"},
+		},
+		{
+			in: "Queen - Bohemian Rhapsody [A Night at the Opera] (5:55)",
+			expected: Item{
+				query:    "Queen - Bohemian Rhapsody [A Night at the Opera] (5:55)",
+				artist:   "Queen",
+				title:    "Bohemian Rhapsody",
+				album:    "A Night at the Opera",
+				duration: 5*time.Minute + 55*time.Second,
+			},
+		},
+		{
+			in: "Queen - Bohemian Rhapsody (Live) (1:05:55)",
+			expected: Item{
+				query:    "Queen - Bohemian Rhapsody (Live) (1:05:55)",
+				artist:   "Queen",
+				title:    "Bohemian Rhapsody (Live)",
+				duration: time.Hour + 5*time.Minute + 55*time.Second,
+			},
+		},
+		{
+			in:       "Bohemian Rhapsody (5:55)",
+			expected: Item{query: "Bohemian Rhapsody (5:55)"},
+		},
+		{
+			in:       " - Bohemian Rhapsody",
+			expected: Item{query: " - Bohemian Rhapsody"},
+		},
+		{
+			in: "artist=Queen; title=Bohemian Rhapsody; album=A Night at the Opera; duration=355; isrc=gbum71029604",
+			expected: Item{
+				line:     "artist=Queen; title=Bohemian Rhapsody; album=A Night at the Opera; duration=355; isrc=gbum71029604",
+				query:    "Queen Bohemian Rhapsody",
+				artist:   "Queen",
+				title:    "Bohemian Rhapsody",
+				album:    "A Night at the Opera",
+				duration: 5*time.Minute + 55*time.Second,
+				isrc:     "GBUM71029604",
+			},
+		},
+		{
+			in:       "isrc=GBUM71029604",
+			expected: Item{query: "isrc=GBUM71029604", isrc: "GBUM71029604"},
+		},
+		{
+			in:       "x=y",
+			expected: Item{query: "x=y"},
+		},
+		{
+			in:       ">>UNRESOLVED§_query",
+			expected: Item{query: "_query", unresolved: true},
+		},
+		{
+			in:       ">>LOCKED§_id§_name§_query",
+			expected: Item{query: "_query", id: "_id", name: "_name", active: true},
+		},
+		{
+			in: ">>LOCKED§_id§_name§Queen - Bohemian Rhapsody",
+			expected: Item{
+				query:  "Queen - Bohemian Rhapsody",
+				artist: "Queen",
+				title:  "Bohemian Rhapsody",
+				id:     "_id",
+				name:   "_name",
+				active: true,
+			},
+		},
+	}
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			actual := ParseItem(test.in)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.in, actual.String())
+		})
+	}
+}
+
+func TestItem_SearchQuery(t *testing.T) {
+	table := map[string]string{
+		"porno for pyros tahitian moon":                           "porno for pyros tahitian moon",
+		"Queen - Bohemian Rhapsody [A Night at the Opera] (5:55)": "Queen - Bohemian Rhapsody",
+		"Queen - Bohemian Rhapsody (5:55) [A Night at the Opera]": "Queen - Bohemian Rhapsody",
+		"Queen - Bohemian Rhapsody (Live) (1:05:55)":              "Queen - Bohemian Rhapsody (Live)",
+		"Bohemian Rhapsody (5:55)":                                "Bohemian Rhapsody",
+		"[A Night at the Opera]":                                  "[A Night at the Opera]",
+		"artist=Queen; title=Bohemian Rhapsody; duration=355":     "Queen Bohemian Rhapsody",
+	}
+	for in, expected := range table {
+		t.Run(in, func(t *testing.T) {
+			assert.Equal(t, expected, ParseItem(in).SearchQuery())
+		})
+	}
+}
@@ -4,32 +4,45 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
-const locked = ">>LOCKED"
+const (
+	locked     = ">>LOCKED"
+	unresolved = ">>UNRESOLVED"
+)
 
 type Item struct {
+	line     string // input line, when it differs from the query
 	query    string
 	id, name string
-	active   bool
-}
+	target   string // owner of the id, empty for legacy locks
 
-func ParseItem(in string) Item {
-	if chunks := strings.Split(in, "§"); len(chunks) == 4 {
-		return Item{
-			query:  chunks[3],
-			id:     chunks[1],
-			name:   chunks[2],
-			active: true,
-		}
-	}
+	artist, title, album string
 
-	return Item{query: in}
+	isrc       string
+	duration   time.Duration
+	active     bool
+	unresolved bool
+}
+
+func NewItem(query string) Item {
+	return Item{query: query}
 }
 
 func (i Item) String() string {
 	if i.active {
-		return fmt.Sprintf("%s§%s§%s§%s", locked, i.id, i.name, i.query)
+		return fmt.Sprintf("%s§%s§%s§%s", locked, i.id, i.name, i.text())
+	}
+	if i.unresolved {
+		return fmt.Sprintf("%s§%s", unresolved, i.text())
+	}
+	return i.text()
+}
+
+func (i Item) text() string {
+	if i.line != "" {
+		return i.line
 	}
 	return i.query
 }
@@ -38,6 +51,11 @@ func (i Item) Active() bool {
 	return i.active
 }
 
+// Unresolved tells if the item was not found by a previous search, see Set.Lock.
+func (i Item) Unresolved() bool {
+	return i.unresolved && !i.active
+}
+
 func (i Item) Query() string {
 	if i.query == "" {
 		panic("empty item query")
@@ -45,6 +63,19 @@ func (i Item) Query() string {
 	return i.query
 }
 
+// SearchQuery returns the query without its trailing [album] and (duration), which only get in the way of free-text searches.
+func (i Item) SearchQuery() string {
+	if rest, _, _ := trimSuffixes(i.Query()); strings.TrimSpace(rest) != "" {
+		return strings.TrimSpace(rest)
+	}
+	return i.Query()
+}
+
+// ID returns the ID of the track the item is locked to, empty when not locked.
+func (i Item) ID() string {
+	return i.id
+}
+
 func (i Item) Name() string {
 	if i.name == "" {
 		panic("empty item name")
@@ -52,40 +83,74 @@ func (i Item) Name() string {
 	return i.name
 }
 
+// Target returns the name of the target the item ID belongs to, empty when unknown.
+func (i Item) Target() string {
+	return i.target
+}
+
+func (i Item) Artist() string {
+	return i.artist
+}
+
+func (i Item) Title() string {
+	return i.title
+}
+
+func (i Item) Album() string {
+	return i.album
+}
+
+func (i Item) ISRC() string {
+	return i.isrc
+}
+
+func (i Item) Duration() time.Duration {
+	return i.duration
+}
+
 func (i Item) WithID(id string) Item {
-	return Item{
-		query:  i.query,
-		id:     id,
-		name:   i.name,
-		active: i.active,
-	}
+	i.id = id
+	return i
 }
 
 func (i Item) WithName(name string) Item {
-	return Item{
-		query:  i.query,
-		id:     i.id,
-		name:   name,
-		active: i.active,
-	}
+	i.name = name
+	return i
+}
+
+func (i Item) WithTarget(target string) Item {
+	i.target = target
+	return i
 }
 
 func (i Item) WithActive(active bool) Item {
-	return Item{
-		query:  i.query,
-		id:     i.id,
-		name:   i.name,
-		active: active,
-	}
+	i.active = active
+	return i
 }
 
 func (i Item) WithQuery(query string) Item {
-	return Item{
-		query:  query,
-		id:     i.id,
-		name:   i.name,
-		active: i.active,
+	i.line = ""
+	i.query = query
+	return i
+}
+
+// WithFields sets the artist, title and album, the query defaults to artist and title when empty.
+func (i Item) WithFields(artist, title, album string) Item {
+	i.artist, i.title, i.album = artist, title, album
+	if i.query == "" {
+		i.query = strings.TrimSpace(artist + " " + title)
 	}
+	return i
+}
+
+func (i Item) WithISRC(isrc string) Item {
+	i.isrc = isrc
+	return i
+}
+
+func (i Item) WithDuration(duration time.Duration) Item {
+	i.duration = duration
+	return i
 }
 
 type Set struct {
@@ -116,6 +181,10 @@ func (c *Set) Put(i int, item Item) (bool, int) {
 
 	c.list[i] = item
 
+	if old.id != "" && old.id != item.id && c.ids[old.id] == i {
+		delete(c.ids, old.id)
+	}
+
 	if item.id != "" {
 		if idx, ok := c.ids[item.id]; ok && i != idx {
 			c.list[i].active = false
@@ -140,7 +209,7 @@ func (c *Set) Slice() ([]string, []string) {
 		}
 
 		if v.query != "" && !v.active {
-			inactive = append(inactive, v.query)
+			inactive = append(inactive, v.text())
 		}
 	}
 
@@ -164,6 +233,25 @@ func (c *Set) Queries() []string {
 	return out
 }
 
+// Lock returns the items to write to a lock file: locked items, and the rest marked as unresolved.
+func (c *Set) Lock() []Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]Item, 0, len(c.list))
+
+	for _, v := range c.list {
+		if v.query == "" {
+			continue
+		}
+
+		v.unresolved = !v.active
+		out = append(out, v)
+	}
+
+	return out
+}
+
 func (c *Set) Empty() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -62,6 +62,17 @@ func TestSet_Put(t *testing.T) {
 		assert.Equal(t, []string{"id_A"}, active)
 		assert.Empty(t, inactive)
 	})
+	t.Run("released value", func(t *testing.T) {
+		s := New(2)
+
+		requirements(t, true, -1, put(s, 0, "_A", true))
+		requirements(t, true, -1, put(s, 0, "_B", true))
+		requirements(t, true, -1, put(s, 1, "_A", true))
+
+		active, inactive := s.Slice()
+		assert.Equal(t, []string{"id_B", "id_A"}, active)
+		assert.Empty(t, inactive)
+	})
 	t.Run("same id and value", func(t *testing.T) {
 		s := New(4)
 
@@ -234,6 +245,26 @@ func TestSet_Put(t *testing.T) {
 	})
 }
 
+func TestSet_Lock(t *testing.T) {
+	s := New(4)
+
+	requirements(t, true, -1, put(s, 0, "_A", true))
+	requirements(t, true, -1, putItem(s, 1, ParseItem("_query")))
+	requirements(t, false, 0, put(s, 3, "_A", true))
+
+	var lines []string
+	for _, item := range s.Lock() {
+		lines = append(lines, item.String())
+	}
+	assert.Equal(t, []string{">>LOCKED§id_A§name_A§query_A", ">>UNRESOLVED§_query", ">>UNRESOLVED§query_A"}, lines)
+
+	for i, line := range lines {
+		item := ParseItem(line)
+		assert.Equal(t, line, item.String())
+		assert.Equal(t, i != 0, item.Unresolved())
+	}
+}
+
 func put(s *Set, i int, v string, a bool) func() (bool, int) {
 	return func() (bool, int) {
 		item := Item{
@@ -0,0 +1,287 @@
+// Package retry provides an HTTP doer that spaces requests out and retries the throttled and failed ones.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Limiter spaces requests out to a maximum rate. It is meant to be shared by every Doer sending requests to the same API.
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter creates a Limiter allowing up to perSecond requests per second.
+func NewLimiter(perSecond int) *Limiter {
+	return &Limiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until a request can be sent, or the context is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, slot.Sub(now))
+}
+
+// Pause holds every request back for the given duration, as asked by a Retry-After header.
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// DefaultRate is the number of requests per second allowed by the limiters of Client.
+// Both Spotify and Deezer throttle clients going over a few requests per second.
+const DefaultRate = 10
+
+var (
+	limiters   = make(map[string]*Limiter)
+	limitersMu sync.Mutex
+)
+
+// Client returns a Doer with a 30 seconds timeout, sharing a Limiter with every other Client of the named API.
+func Client(api string, opts ...Option) *Doer {
+	limitersMu.Lock()
+	l, ok := limiters[api]
+	if !ok {
+		l = NewLimiter(DefaultRate)
+		limiters[api] = l
+	}
+	limitersMu.Unlock()
+
+	return New(&http.Client{Timeout: 30 * time.Second}, append([]Option{RateLimit(l)}, opts...)...)
+}
+
+// Doer retries requests failing with a transport error, or a 429 or 5xx status, honouring the Retry-After header
+// and otherwise waiting an exponential backoff with jitter between attempts.
+// Non-idempotent requests, like POST, are only retried on a 429 status or when they could not be sent at all,
+// as the service may have applied them before failing.
+type Doer struct {
+	doer     doer
+	limiter  *Limiter
+	attempts int
+
+	baseDelay, maxDelay time.Duration
+	maxRetryAfter       time.Duration
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option represents a Doer constructor option.
+type Option func(*Doer)
+
+// Attempts sets the maximum number of attempts per request.
+func Attempts(n int) Option {
+	return func(d *Doer) {
+		d.attempts = n
+	}
+}
+
+// Backoff sets the first and the maximum wait between attempts, when the response has no Retry-After header.
+func Backoff(base, maxDelay time.Duration) Option {
+	return func(d *Doer) {
+		d.baseDelay, d.maxDelay = base, maxDelay
+	}
+}
+
+// MaxRetryAfter sets the longest Retry-After honoured, longer ones make the response be returned as it is.
+func MaxRetryAfter(v time.Duration) Option {
+	return func(d *Doer) {
+		d.maxRetryAfter = v
+	}
+}
+
+// RateLimit makes every attempt wait for the given Limiter.
+func RateLimit(l *Limiter) Option {
+	return func(d *Doer) {
+		d.limiter = l
+	}
+}
+
+// New creates a Doer wrapping the given one.
+func New(doer doer, opts ...Option) *Doer {
+	out := &Doer{
+		doer:          doer,
+		attempts:      5,
+		baseDelay:     500 * time.Millisecond,
+		maxDelay:      30 * time.Second,
+		maxRetryAfter: time.Minute,
+		sleep:         sleep,
+	}
+
+	for _, o := range opts {
+		o(out)
+	}
+
+	return out
+}
+
+// Do sends the request, retrying it as needed. The last response or error is returned once attempts run out.
+// Requests with a body are only retried when it can be read again, see http.Request.GetBody.
+func (d *Doer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if d.limiter != nil {
+			if err := d.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := d.doer.Do(req)
+
+		wait, retry := d.backoff(attempt, req, res, err)
+		if !retry || attempt >= d.attempts || !rewindable(req) {
+			return res, err
+		}
+
+		if res != nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		if err := d.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff tells how long to wait before retrying, and if the request must be retried at all.
+func (d *Doer) backoff(attempt int, req *http.Request, res *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+		return d.jitter(attempt), idempotent(req) || unsent(err)
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests && (res.StatusCode < http.StatusInternalServerError || !idempotent(req)) {
+		return 0, false
+	}
+
+	wait, ok := retryAfter(res.Header.Get("Retry-After"))
+	if !ok {
+		return d.jitter(attempt), true
+	}
+
+	if wait > d.maxRetryAfter {
+		return 0, false
+	}
+
+	if d.limiter != nil {
+		d.limiter.Pause(wait)
+	}
+
+	return wait, true
+}
+
+// jitter returns a random wait between half and the whole exponential delay of the attempt.
+func (d *Doer) jitter(attempt int) time.Duration {
+	delay := d.maxDelay
+	if shift := attempt - 1; shift < 32 && d.baseDelay<<shift < d.maxDelay {
+		delay = d.baseDelay << shift
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+// idempotent tells if sending the request twice has the same effect as sending it once.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// unsent tells if the error proves the request never reached the service, failing to resolve its host or to connect.
+func unsent(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	out := req.Clone(req.Context())
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+
+	return out, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
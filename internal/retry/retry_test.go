@@ -0,0 +1,268 @@
+package retry
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type response struct {
+	status     int
+	retryAfter string
+}
+
+func TestDoer_Do(t *testing.T) {
+	table := []struct {
+		name           string
+		method         string
+		responses      []response
+		expectedStatus int
+		expectedCalls  int
+		expectedWaits  []time.Duration
+	}{
+		{
+			name:           "ok",
+			responses:      []response{{status: http.StatusOK}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+		},
+		{
+			name:           "client error",
+			responses:      []response{{status: http.StatusNotFound}},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  1,
+		},
+		{
+			name:           "retry after seconds",
+			responses:      []response{{status: http.StatusTooManyRequests, retryAfter: "2"}, {status: http.StatusOK}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{2 * time.Second},
+		},
+		{
+			name:   "backoff",
+			method: http.MethodPut,
+			responses: []response{
+				{status: http.StatusTooManyRequests},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusBadGateway},
+				{status: http.StatusOK},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  4,
+			expectedWaits:  []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond},
+		},
+		{
+			name:           "post server error",
+			method:         http.MethodPost,
+			responses:      []response{{status: http.StatusBadGateway}, {status: http.StatusOK}},
+			expectedStatus: http.StatusBadGateway,
+			expectedCalls:  1,
+		},
+		{
+			name:           "post retry after",
+			method:         http.MethodPost,
+			responses:      []response{{status: http.StatusTooManyRequests, retryAfter: "1"}, {status: http.StatusOK}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{time.Second},
+		},
+		{
+			name: "attempts run out",
+			responses: []response{
+				{status: http.StatusTooManyRequests, retryAfter: "1"},
+				{status: http.StatusTooManyRequests, retryAfter: "1"},
+				{status: http.StatusTooManyRequests, retryAfter: "1"},
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCalls:  3,
+			expectedWaits:  []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:           "retry after too long",
+			responses:      []response{{status: http.StatusTooManyRequests, retryAfter: "3600"}},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCalls:  1,
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, cmp.Or(test.method, http.MethodPut), req.Method)
+				assert.Equal(t, "_BODY", tests.ReadBody(t, req))
+
+				r := test.responses[calls.Add(1)-1]
+				if r.retryAfter != "" {
+					w.Header().Set("Retry-After", r.retryAfter)
+				}
+				w.WriteHeader(r.status)
+			}))
+			defer svr.Close()
+
+			var waits []time.Duration
+			doer := New(http.DefaultClient, Attempts(len(test.responses)), Backoff(100*time.Millisecond, 300*time.Millisecond))
+			doer.sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			req, err := http.NewRequest(cmp.Or(test.method, http.MethodPut), svr.URL, strings.NewReader("_BODY"))
+			require.NoError(t, err)
+
+			res, err := doer.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			assert.Equal(t, test.expectedCalls, int(calls.Load()))
+
+			require.Len(t, waits, len(test.expectedWaits))
+			for i, expected := range test.expectedWaits {
+				if test.responses[i].retryAfter != "" {
+					assert.Equal(t, expected, waits[i])
+				} else {
+					assert.GreaterOrEqual(t, waits[i], expected/2)
+					assert.LessOrEqual(t, waits[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestDoer_Do_transportError(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	var calls atomic.Int32
+	hangUp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if assert.NoError(t, err) {
+			_ = conn.Close()
+		}
+	}))
+	defer hangUp.Close()
+
+	table := []struct {
+		name          string
+		method        string
+		url           string
+		expectedCalls int
+		expectedWaits int
+	}{
+		{name: "get hung up", method: http.MethodGet, url: hangUp.URL, expectedCalls: 3, expectedWaits: 2},
+		{name: "post hung up", method: http.MethodPost, url: hangUp.URL, expectedCalls: 1},
+		{name: "post not connected", method: http.MethodPost, url: closed.URL, expectedWaits: 2},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			calls.Store(0)
+
+			var waits int
+			doer := New(http.DefaultClient, Attempts(3))
+			doer.sleep = func(context.Context, time.Duration) error {
+				waits++
+				return nil
+			}
+
+			req, err := http.NewRequest(test.method, test.url, strings.NewReader("_BODY"))
+			require.NoError(t, err)
+
+			_, err = doer.Do(req)
+			require.Error(t, err)
+
+			assert.Equal(t, test.expectedCalls, int(calls.Load()))
+			assert.Equal(t, test.expectedWaits, waits)
+		})
+	}
+}
+
+func TestDoer_Do_canceled(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer svr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, svr.URL, nil)
+	require.NoError(t, err)
+
+	_, err = New(http.DefaultClient).Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDoer_Do_limiter(t *testing.T) {
+	var calls atomic.Int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer svr.Close()
+
+	limiter := NewLimiter(100)
+	doer := New(http.DefaultClient, RateLimit(limiter))
+	doer.sleep = func(context.Context, time.Duration) error {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, svr.URL, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	res, err := doer.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond, "the limiter holds the retry back")
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	limiter := NewLimiter(50)
+
+	start := time.Now()
+	for range 6 {
+		require.NoError(t, limiter.Wait(context.Background()))
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func Test_retryAfter(t *testing.T) {
+	table := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{value: "", ok: false},
+		{value: "abc", ok: false},
+		{value: "0", expected: 0, ok: true},
+		{value: "-5", expected: 0, ok: true},
+		{value: "120", expected: 2 * time.Minute, ok: true},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", expected: 0, ok: true},
+	}
+	for _, test := range table {
+		t.Run(test.value, func(t *testing.T) {
+			actual, ok := retryAfter(test.value)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,369 @@
+// Package mock provides a playlists.Target serving its searches from a JSON catalog and recording its playlists on disk,
+// for offline demos and end-to-end tests.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/agukrapo/playlist-creator/internal/files"
+	"github.com/agukrapo/playlist-creator/playlists"
+)
+
+// Track is a catalog track.
+type Track struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Artists     []string `json:"artists"`
+	Album       string   `json:"album,omitempty"`
+	ISRC        string   `json:"isrc,omitempty"`
+	Duration    int      `json:"duration,omitempty"` // seconds
+	Unavailable bool     `json:"unavailable,omitempty"`
+}
+
+func (t Track) track() playlists.Track {
+	return playlists.Track{
+		ID:          t.ID,
+		Name:        fmt.Sprintf("%s - %s <%s>", strings.Join(t.Artists, ", "), t.Title, t.Album),
+		Title:       t.Title,
+		Artists:     t.Artists,
+		Album:       t.Album,
+		ISRC:        t.ISRC,
+		Duration:    time.Duration(t.Duration) * time.Second,
+		Unavailable: t.Unavailable,
+	}
+}
+
+// Playlist is either a catalog playlist or one recorded by the Target.
+type Playlist struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	Public        bool     `json:"public,omitempty"`
+	Collaborative bool     `json:"collaborative,omitempty"`
+	Tracks        []string `json:"tracks"`
+}
+
+// Catalog is the JSON document the Target reads its tracks and initial playlists from.
+type Catalog struct {
+	Tracks    []Track    `json:"tracks"`
+	Playlists []Playlist `json:"playlists"`
+}
+
+// Target is a playlists.Target backed by a Catalog. The playlists it creates are written to its directory,
+// a JSON file each, along with their cover image, and are listed next to the catalog ones.
+type Target struct {
+	catalog string
+	dir     string
+
+	mu        sync.Mutex
+	tracks    map[string]Track
+	order     []string // track IDs in catalog order
+	playlists map[string]*Playlist
+	loaded    bool
+}
+
+// New creates a Target reading the catalog file and recording its playlists under dir.
+func New(catalog, dir string) *Target {
+	return &Target{
+		catalog: filepath.Clean(catalog),
+		dir:     filepath.Clean(dir),
+	}
+}
+
+func (t *Target) Name() string {
+	return "mock"
+}
+
+// ValidID tells if the ID is a decimal number, the format of the catalog track IDs.
+func (t *Target) ValidID(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// Setup loads the catalog and the recorded playlists, once.
+func (t *Target) Setup(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.loaded {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(t.catalog)
+	if err != nil {
+		return err
+	}
+
+	var c Catalog
+	if err := json.Unmarshal(bytes, &c); err != nil {
+		return fmt.Errorf("%s: %w", t.catalog, err)
+	}
+
+	t.tracks = make(map[string]Track, len(c.Tracks))
+	t.order = make([]string, 0, len(c.Tracks))
+	for _, track := range c.Tracks {
+		if _, ok := t.tracks[track.ID]; ok || !t.ValidID(track.ID) {
+			return fmt.Errorf("%s: invalid or duplicated track ID %q", t.catalog, track.ID)
+		}
+		t.tracks[track.ID] = track
+		t.order = append(t.order, track.ID)
+	}
+
+	t.playlists = make(map[string]*Playlist, len(c.Playlists))
+	for _, p := range c.Playlists {
+		t.playlists[p.ID] = &p
+	}
+
+	recorded, err := filepath.Glob(filepath.Join(t.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range recorded {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var p Playlist
+		if err := json.Unmarshal(bytes, &p); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		t.playlists[p.ID] = &p
+	}
+
+	t.loaded = true
+
+	return nil
+}
+
+// SearchTracks returns the tracks whose title, artists and album contain every word of the query.
+func (t *Target) SearchTracks(ctx context.Context, query string) ([]playlists.Track, error) {
+	query = strings.TrimSpace(query)
+
+	return t.search(ctx, func(track Track) bool {
+		return query != "" && contains(words(strings.Join(track.Artists, " ")+" "+track.Title+" "+track.Album), words(query))
+	})
+}
+
+func (t *Target) SearchISRC(ctx context.Context, isrc string) ([]playlists.Track, error) {
+	return t.search(ctx, func(track Track) bool {
+		return isrc != "" && strings.EqualFold(track.ISRC, isrc)
+	})
+}
+
+// SearchFields returns the tracks matching every given field, ignoring the empty ones.
+func (t *Target) SearchFields(ctx context.Context, fields playlists.Fields) ([]playlists.Track, error) {
+	if fields == (playlists.Fields{}) {
+		return nil, nil
+	}
+
+	return t.search(ctx, func(track Track) bool {
+		return contains(words(strings.Join(track.Artists, " ")), words(fields.Artist)) &&
+			contains(words(track.Title), words(fields.Title)) &&
+			contains(words(track.Album), words(fields.Album))
+	})
+}
+
+func (t *Target) search(ctx context.Context, match func(Track) bool) ([]playlists.Track, error) {
+	if err := t.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []playlists.Track
+	for _, id := range t.order {
+		if track := t.tracks[id]; match(track) {
+			out = append(out, track.track())
+		}
+	}
+
+	return out, nil
+}
+
+// CreatePlaylist records an empty playlist, writing its cover image, if any, next to it.
+func (t *Target) CreatePlaylist(ctx context.Context, name string, opts playlists.PlaylistOptions) (string, error) {
+	if err := t.ready(ctx); err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := len(t.playlists) + 1
+	for t.playlists[fmt.Sprintf("playlist-%d", n)] != nil {
+		n++
+	}
+	id := fmt.Sprintf("playlist-%d", n)
+
+	p := &Playlist{
+		ID:            id,
+		Name:          name,
+		Description:   opts.Description,
+		Public:        opts.Public,
+		Collaborative: opts.Collaborative,
+		Tracks:        []string{},
+	}
+
+	if err := t.write(p); err != nil {
+		return "", err
+	}
+
+	if len(opts.Cover) != 0 {
+		if err := files.WriteFile(filepath.Join(t.dir, id+".jpg"), opts.Cover, 0o600); err != nil {
+			t.playlists[id] = p
+			return id, &playlists.CoverError{Err: err}
+		}
+	}
+
+	t.playlists[id] = p
+
+	return id, nil
+}
+
+// PopulatePlaylist appends the tracks to the playlist, ignoring the position, failing when any of them is not in the catalog.
+func (t *Target) PopulatePlaylist(ctx context.Context, playlistID string, _ int, tracks []string) error {
+	return t.update(ctx, playlistID, func(p *Playlist) error {
+		for _, id := range tracks {
+			if _, ok := t.tracks[id]; !ok {
+				return fmt.Errorf("track %q: %w", id, playlists.ErrTrackNotFound)
+			}
+		}
+
+		p.Tracks = append(p.Tracks, tracks...)
+		return nil
+	})
+}
+
+// RemoveTracks removes every occurrence of the tracks from the playlist.
+func (t *Target) RemoveTracks(ctx context.Context, playlistID string, tracks []string) error {
+	return t.update(ctx, playlistID, func(p *Playlist) error {
+		p.Tracks = slices.DeleteFunc(p.Tracks, func(id string) bool {
+			return slices.Contains(tracks, id)
+		})
+		return nil
+	})
+}
+
+func (t *Target) Playlists(ctx context.Context) ([]playlists.Playlist, error) {
+	if err := t.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]playlists.Playlist, 0, len(t.playlists))
+	for _, p := range t.playlists {
+		out = append(out, playlists.Playlist{ID: p.ID, Name: p.Name})
+	}
+
+	slices.SortFunc(out, func(a, b playlists.Playlist) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return out, nil
+}
+
+func (t *Target) PlaylistTracks(ctx context.Context, playlistID string) ([]playlists.Track, error) {
+	if err := t.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.playlists[playlistID]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", playlistID, playlists.ErrPlaylistNotFound)
+	}
+
+	out := make([]playlists.Track, 0, len(p.Tracks))
+	for _, id := range p.Tracks {
+		out = append(out, t.tracks[id].track())
+	}
+
+	return out, nil
+}
+
+// ready loads the target when Setup was not called.
+func (t *Target) ready(ctx context.Context) error {
+	if err := t.Setup(ctx); err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
+
+	return nil
+}
+
+// update changes a playlist and records it, catalog playlists are recorded on their first change.
+func (t *Target) update(ctx context.Context, playlistID string, fn func(p *Playlist) error) error {
+	if err := t.ready(ctx); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.playlists[playlistID]
+	if !ok {
+		return fmt.Errorf("%q: %w", playlistID, playlists.ErrPlaylistNotFound)
+	}
+
+	changed := *p
+	changed.Tracks = slices.Clone(p.Tracks)
+
+	if err := fn(&changed); err != nil {
+		return err
+	}
+
+	if err := t.write(&changed); err != nil {
+		return err
+	}
+
+	*p = changed
+
+	return nil
+}
+
+func (t *Target) write(p *Playlist) error {
+	bytes, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return files.WriteFile(filepath.Join(t.dir, filepath.Base(p.ID)+".json"), bytes, 0o600)
+}
+
+// contains tells if every word is in the text words.
+func contains(text, words []string) bool {
+	for _, w := range words {
+		if !slices.Contains(text, w) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// words normalizes the text into lower case alphanumeric words.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
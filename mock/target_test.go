@@ -0,0 +1,160 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/agukrapo/playlist-creator/playlists"
+	"github.com/agukrapo/playlist-creator/playlists/playliststest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarget_search(t *testing.T) {
+	target := New("test-data/catalog.json", t.TempDir())
+	ctx := context.Background()
+
+	table := []struct {
+		name     string
+		search   func() ([]playlists.Track, error)
+		expected []string
+	}{
+		{
+			name:     "query",
+			search:   func() ([]playlists.Track, error) { return target.SearchTracks(ctx, "queen bohemian rhapsody") },
+			expected: []string{"3", "4"},
+		},
+		{
+			name:     "query words in any order",
+			search:   func() ([]playlists.Track, error) { return target.SearchTracks(ctx, "Pets - porno for pyros") },
+			expected: []string{"2"},
+		},
+		{
+			name:   "empty query",
+			search: func() ([]playlists.Track, error) { return target.SearchTracks(ctx, " ") },
+		},
+		{
+			name:     "isrc",
+			search:   func() ([]playlists.Track, error) { return target.SearchISRC(ctx, "uswb19500351") },
+			expected: []string{"1"},
+		},
+		{
+			name: "fields",
+			search: func() ([]playlists.Track, error) {
+				return target.SearchFields(ctx, playlists.Fields{Artist: "Queen", Title: "Bohemian Rhapsody", Album: "Live Aid"})
+			},
+			expected: []string{"4"},
+		},
+		{
+			name:   "no fields",
+			search: func() ([]playlists.Track, error) { return target.SearchFields(ctx, playlists.Fields{}) },
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := test.search()
+			require.NoError(t, err)
+
+			var actual []string
+			for _, m := range matches {
+				actual = append(actual, m.ID)
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	matches, err := target.SearchTracks(ctx, "live aid")
+	require.NoError(t, err)
+	assert.Equal(t, []playlists.Track{{
+		ID:          "4",
+		Name:        "Queen - Bohemian Rhapsody <Live Aid>",
+		Title:       "Bohemian Rhapsody",
+		Artists:     []string{"Queen"},
+		Album:       "Live Aid",
+		Duration:    6 * time.Minute,
+		Unavailable: true,
+	}}, matches)
+}
+
+func TestTarget_playlists(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	target := New("test-data/catalog.json", dir)
+
+	id, err := target.CreatePlaylist(ctx, "New", playlists.PlaylistOptions{Description: "_DESCRIPTION", Cover: []byte{0xFF, 0xD8, 0xFF}})
+	require.NoError(t, err)
+	assert.Equal(t, "playlist-2", id)
+
+	require.NoError(t, target.PopulatePlaylist(ctx, id, 0, []string{"1", "2", "3"}))
+	require.NoError(t, target.RemoveTracks(ctx, id, []string{"2"}))
+
+	err = target.PopulatePlaylist(ctx, id, 3, []string{"1", "_UNKNOWN"})
+	require.ErrorIs(t, err, playlists.ErrTrackNotFound)
+
+	err = target.PopulatePlaylist(ctx, "_UNKNOWN", 0, []string{"1"})
+	require.ErrorIs(t, err, playlists.ErrPlaylistNotFound)
+
+	assert.JSONEq(t, `{"id":"playlist-2","name":"New","description":"_DESCRIPTION","tracks":["1","3"]}`, tests.ReadFile(t, dir+"/playlist-2.json"))
+	assert.Equal(t, "\xFF\xD8\xFF", tests.ReadFile(t, dir+"/playlist-2.jpg"))
+
+	reopened := New("test-data/catalog.json", dir)
+
+	list, err := reopened.Playlists(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []playlists.Playlist{{ID: "favorites", Name: "Favorites"}, {ID: "playlist-2", Name: "New"}}, list)
+
+	tracks, err := reopened.PlaylistTracks(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, tracks, 2)
+	assert.Equal(t, "Porno For Pyros - Tahitian Moon <Good God's Urge>", tracks[0].Name)
+	assert.Equal(t, "3", tracks[1].ID)
+}
+
+func TestTarget_Setup(t *testing.T) {
+	err := New("test-data/missing.json", t.TempDir()).Setup(context.Background())
+	require.Error(t, err)
+
+	catalog := filepath.Join(t.TempDir(), "catalog.json")
+	require.NoError(t, os.WriteFile(catalog, []byte(`{"tracks":[{"id":"spotify:track:1","title":"Pets"}]}`), 0o600))
+
+	err = New(catalog, t.TempDir()).Setup(context.Background())
+	require.EqualError(t, err, catalog+`: invalid or duplicated track ID "spotify:track:1"`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = New("test-data/catalog.json", t.TempDir()).SearchTracks(ctx, "queen")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTarget_ValidID(t *testing.T) {
+	table := map[string]bool{
+		"1":               true,
+		"":                false,
+		"-1":              false,
+		"playlist-1":      false,
+		"spotify:track:1": false,
+	}
+	for id, expected := range table {
+		t.Run(id, func(t *testing.T) {
+			assert.Equal(t, expected, New("", "").ValidID(id))
+		})
+	}
+}
+
+func TestTarget_conformance(t *testing.T) {
+	playliststest.Suite{
+		New: func(t *testing.T) playlists.Target {
+			return New("test-data/catalog.json", t.TempDir())
+		},
+		Query:  "porno for pyros",
+		ISRC:   "USWB19500351",
+		Fields: playlists.Fields{Artist: "Queen", Title: "Bohemian Rhapsody"},
+		Tracks: []string{"1", "2", "3"},
+	}.Run(t)
+}
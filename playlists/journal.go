@@ -0,0 +1,93 @@
+package playlists
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Progress of a push, recorded after every batch of added tracks.
+type Progress struct {
+	Target     string   `json:"target"`
+	Name       string   `json:"name"`
+	PlaylistID string   `json:"playlist_id"`
+	Tracks     []string `json:"tracks"`
+	Added      int      `json:"added"`
+}
+
+// Journal persists the progress of a push, so a failed one can be resumed.
+type Journal interface {
+	// Load returns the recorded progress, reporting false when there is none.
+	Load() (Progress, bool, error)
+	Save(p Progress) error
+	// Done discards the progress of a finished push.
+	Done() error
+}
+
+// ErrNothingToResume is returned by Resume when the journal holds no progress.
+var ErrNothingToResume = errors.New("nothing to resume")
+
+// pushBatch is the number of tracks added between journal updates.
+const pushBatch = 100
+
+// Resume continues a push recorded by the journal, adding the remaining tracks to the same playlist.
+func (m *Manager) Resume(ctx context.Context, journal Journal) error {
+	p, ok, err := journal.Load()
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if !ok {
+		return ErrNothingToResume
+	}
+
+	if p.Target != m.target.Name() {
+		return fmt.Errorf("journal: playlist %q was pushed to %s", p.Name, p.Target)
+	}
+
+	if err := m.target.Setup(ctx); err != nil {
+		return fmt.Errorf("%s: setup: %w", m.target.Name(), err)
+	}
+
+	return m.populate(ctx, &p, journal)
+}
+
+// populate adds the remaining tracks in batches, saving the progress after each one.
+func (m *Manager) populate(ctx context.Context, p *Progress, journal Journal) error {
+	for p.Added < len(p.Tracks) {
+		end := min(p.Added+pushBatch, len(p.Tracks))
+
+		if err := m.target.PopulatePlaylist(ctx, p.PlaylistID, p.Added, p.Tracks[p.Added:end]); err != nil {
+			var perr *PopulateError
+			if errors.As(err, &perr) {
+				p.Added += perr.Added
+				err = perr.Err
+			}
+
+			if journal != nil {
+				err = errors.Join(err, journal.Save(*p))
+			}
+
+			if p.Added != 0 {
+				err = &PopulateError{Added: p.Added, Err: err}
+			}
+
+			return fmt.Errorf("%s: populate playlist: %w", m.target.Name(), err)
+		}
+
+		p.Added = end
+
+		if journal != nil {
+			if err := journal.Save(*p); err != nil {
+				return fmt.Errorf("journal: %w", err)
+			}
+		}
+	}
+
+	if journal != nil {
+		if err := journal.Done(); err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -1,93 +1,551 @@
 package playlists
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/agukrapo/playlist-creator/internal/results"
 	"golang.org/x/sync/errgroup"
 )
 
-var ErrTrackNotFound = errors.New("track not found")
+var (
+	ErrTrackNotFound    = errors.New("track not found")
+	ErrPlaylistNotFound = errors.New("playlist not found")
+
+	// ErrUnauthorized is wrapped by targets when their credentials are missing, invalid or expired.
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// PopulateError is returned by PopulatePlaylist when it fails after some tracks were already added.
+type PopulateError struct {
+	Added int
+	Err   error
+}
+
+func (e *PopulateError) Error() string {
+	return fmt.Sprintf("%d tracks already added: %v", e.Added, e.Err)
+}
+
+func (e *PopulateError) Unwrap() error {
+	return e.Err
+}
+
+// CoverError is returned by CreatePlaylist, along with the playlist ID, when the playlist was created but its cover
+// image could not be uploaded.
+type CoverError struct {
+	Err error
+}
+
+func (e *CoverError) Error() string {
+	return fmt.Sprintf("cover not uploaded: %v", e.Err)
+}
+
+func (e *CoverError) Unwrap() error {
+	return e.Err
+}
 
 type Track struct {
 	ID, Name string
+	Title    string
+	Artists  []string
+	Album    string
+	ISRC     string
+	Duration time.Duration
+
+	// Unavailable is set when the target reports the track can't be played, e.g. in the user region.
+	Unavailable bool `json:",omitempty"`
 }
 
-type Target interface {
+// Query builds a search query to find the track on other targets.
+func (t Track) Query() string {
+	if t.Title == "" {
+		return t.Name
+	}
+
+	return strings.TrimSpace(strings.Join(t.Artists, " ") + " " + t.Title)
+}
+
+// Fields narrows a search down to the given track attributes, empty ones are ignored.
+type Fields struct {
+	Artist, Title, Album string
+}
+
+type Playlist struct {
+	ID, Name string
+}
+
+// maxCoverSize is the largest cover image accepted, as Spotify limits the base64 encoded upload to 256 KB.
+const maxCoverSize = 190 * 1024
+
+// PlaylistOptions are the settings of a new playlist, the zero value creates a private one.
+type PlaylistOptions struct {
+	Public        bool
+	Collaborative bool
+	Description   string
+	Cover         []byte // JPEG image, optional
+}
+
+// Validate checks the options are supported by every target.
+func (o PlaylistOptions) Validate() error {
+	if o.Public && o.Collaborative {
+		return errors.New("collaborative playlists can't be public")
+	}
+
+	if len(o.Cover) == 0 {
+		return nil
+	}
+
+	if !bytes.HasPrefix(o.Cover, []byte{0xFF, 0xD8, 0xFF}) {
+		return errors.New("cover image is not a JPEG")
+	}
+
+	if len(o.Cover) > maxCoverSize {
+		return fmt.Errorf("cover image bigger than %d KB", maxCoverSize/1024)
+	}
+
+	return nil
+}
+
+// Source is a target whose playlists can be read.
+type Source interface {
 	Name() string
 	Setup(ctx context.Context) error
+	Playlists(ctx context.Context) ([]Playlist, error)
+	PlaylistTracks(ctx context.Context, playlistID string) ([]Track, error)
+}
+
+type Target interface {
+	Source
 	SearchTracks(ctx context.Context, query string) (matches []Track, err error)
-	CreatePlaylist(ctx context.Context, name string) (playlistID string, err error)
-	PopulatePlaylist(ctx context.Context, playlistID string, tracks []string) error
+	SearchISRC(ctx context.Context, isrc string) (matches []Track, err error)
+	SearchFields(ctx context.Context, fields Fields) (matches []Track, err error)
+	// ValidID tells if the track ID has the target format, used to vet the locks that don't name their target.
+	ValidID(id string) bool
+	CreatePlaylist(ctx context.Context, name string, opts PlaylistOptions) (playlistID string, err error)
+	// PopulatePlaylist adds the tracks to the playlist, the first one at position, which is the number of tracks already there.
+	// Targets that always append may ignore the position.
+	PopulatePlaylist(ctx context.Context, playlistID string, position int, tracks []string) error
+	RemoveTracks(ctx context.Context, playlistID string, tracks []string) error
 }
 
 type Manager struct {
 	target         Target
 	maxConcurrency int
+
+	retries    int           // extra rounds for the items whose search failed
+	retryDelay time.Duration // wait before the first extra round, doubled on every following one
+
+	unavailable Availability
 }
 
 func NewManager(target Target, maxConcurrency int) *Manager {
 	return &Manager{
 		target:         target,
 		maxConcurrency: maxConcurrency,
+		retries:        2,
+		retryDelay:     time.Second,
+		unavailable:    Demote,
 	}
 }
 
-type Callback func(i int, item results.Item, matches []Track)
+// SetUnavailable sets what to do with the matches the target can't play, Demote by default.
+func (m *Manager) SetUnavailable(a Availability) {
+	m.unavailable = a
+}
+
+// Status tells how an item was gathered.
+type Status int
+
+const (
+	Found    Status = iota // locked or matched
+	NotFound               // searched without matches
+	Failed                 // every search attempt failed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Found:
+		return "found"
+	case NotFound:
+		return "not found"
+	default:
+		return "failed"
+	}
+}
+
+// Result is the outcome of gathering an item.
+type Result struct {
+	Status  Status
+	Matches []Match // ranked best first, empty for trusted locked items
+	Err     error   // the last search error of Failed items
+}
 
+// Callback receives the result of every item, once. Searched items are tagged with the target name, so they can be locked to it.
+type Callback func(i int, item results.Item, res Result)
+
+// Gather searches the items concurrently. Items whose search fails are retried on their own a few times, then reported as Failed,
+// without stopping the rest. Only rejected credentials and canceled contexts end the whole gathering.
+// An error is returned as well when no item is found at all.
 func (m *Manager) Gather(ctx context.Context, songs []results.Item, fn Callback) error {
 	if err := m.target.Setup(ctx); err != nil {
 		return fmt.Errorf("%s: setup: %w", m.target.Name(), err)
 	}
 
+	pending := make([]int, len(songs))
+	for i := range songs {
+		pending[i] = i
+	}
+
+	var (
+		found    atomic.Uint64
+		firstErr error
+		delay    = m.retryDelay
+	)
+
+	for round := 0; ; round++ {
+		if round != 0 {
+			if err := wait(ctx, delay); err != nil {
+				return err
+			}
+			delay *= 2
+		}
+
+		failed, err := m.gather(ctx, songs, pending, fn, &found, round == m.retries)
+		if err != nil {
+			return err
+		}
+
+		if len(failed) == 0 {
+			break
+		}
+
+		pending = slices.Sorted(maps.Keys(failed))
+		firstErr = failed[pending[0]]
+
+		if round == m.retries {
+			break
+		}
+	}
+
+	if found.Load() == 0 {
+		if firstErr != nil {
+			return fmt.Errorf("no tracks found: %w", firstErr)
+		}
+		return errors.New("no tracks found")
+	}
+
+	return nil
+}
+
+// gather runs a round over the pending items, returning the search errors by item index.
+// Failed items are only passed to fn on the last round, fatal errors end the round early.
+func (m *Manager) gather(ctx context.Context, songs []results.Item, pending []int, fn Callback, found *atomic.Uint64, last bool) (map[int]error, error) {
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(m.maxConcurrency)
 
-	var count atomic.Uint64
+	var (
+		failed = make(map[int]error)
+		mu     sync.Mutex
+	)
+
+	for _, i := range pending {
+		song := songs[i]
 
-	for i, song := range songs {
 		g.Go(func() error {
-			if song.Active() {
-				count.Add(1)
-				fn(i, song, nil)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if m.Trusted(song) {
+				found.Add(1)
+				fn(i, song, Result{Status: Found})
 				return nil
 			}
 
-			query := song.Query()
-			matches, err := m.target.SearchTracks(ctx, query)
+			if song.Active() {
+				song = song.WithActive(false).WithID("")
+			}
+			song = song.WithTarget(m.target.Name())
+
+			matches, err := m.search(ctx, song)
 			if err != nil {
-				return fmt.Errorf("%s: searching %q: %w", m.target.Name(), query, err)
+				err = fmt.Errorf("%s: %w", m.target.Name(), err)
+				if fatal(ctx, err) {
+					return err
+				}
+
+				mu.Lock()
+				failed[i] = err
+				mu.Unlock()
+
+				if last {
+					fn(i, song, Result{Status: Failed, Err: err})
+				}
+				return nil
 			}
 
-			count.Add(uint64(len(matches)))
-			fn(i, song, matches)
+			ranked := m.rank(song, matches)
+			if len(ranked) == 0 {
+				fn(i, song, Result{Status: NotFound})
+				return nil
+			}
+
+			found.Add(1)
+			fn(i, song, Result{Status: Found, Matches: ranked})
 			return nil
 		})
 	}
 
 	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+// fatal tells if the error would make every other search fail as well.
+func fatal(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, ErrUnauthorized) || errors.Is(err, context.Canceled)
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Trusted tells if the item is locked to a track of the manager target.
+// Locks without target, from legacy lock files or inputs with IDs, are trusted when the ID has the target format.
+func (m *Manager) Trusted(item results.Item) bool {
+	if !item.Active() {
+		return false
+	}
+
+	if item.Target() == "" {
+		return m.target.ValidID(item.ID())
+	}
+
+	return item.Target() == m.target.Name()
+}
+
+// Search looks a single item up, returning its matches ranked best first.
+func (m *Manager) Search(ctx context.Context, item results.Item) ([]Match, error) {
+	matches, err := m.search(ctx, item)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.target.Name(), err)
+	}
+
+	return m.rank(item, matches), nil
+}
+
+// rank ranks the matches, then demotes or drops the unavailable ones as set.
+func (m *Manager) rank(item results.Item, matches []Track) []Match {
+	out := Rank(item, matches)
+
+	switch m.unavailable {
+	case Demote:
+		slices.SortStableFunc(out, func(a, b Match) int {
+			switch {
+			case a.Unavailable == b.Unavailable:
+				return 0
+			case a.Unavailable:
+				return 1
+			default:
+				return -1
+			}
+		})
+	case Drop:
+		out = slices.DeleteFunc(out, func(m Match) bool {
+			return m.Unavailable
+		})
+	}
+
+	return out
+}
+
+// search looks the item up by ISRC, then by its fields when known, falling back to a free-text search.
+func (m *Manager) search(ctx context.Context, item results.Item) ([]Track, error) {
+	if isrc := item.ISRC(); isrc != "" {
+		matches, err := m.target.SearchISRC(ctx, isrc)
+		if err != nil {
+			return nil, fmt.Errorf("searching ISRC %s: %w", isrc, err)
+		}
+
+		if len(matches) != 0 {
+			return matches, nil
+		}
+	}
+
+	if item.Title() != "" {
+		fields := Fields{Artist: item.Artist(), Title: item.Title(), Album: item.Album()}
+		matches, err := m.target.SearchFields(ctx, fields)
+		if err != nil {
+			return nil, fmt.Errorf("searching %+v: %w", fields, err)
+		}
+
+		if len(matches) != 0 {
+			return matches, nil
+		}
+	}
+
+	query := item.SearchQuery()
+	matches, err := m.target.SearchTracks(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("searching %q: %w", query, err)
+	}
+
+	return matches, nil
+}
+
+// Push creates a playlist with the given options and songs. When journal isn't nil, the progress is recorded
+// so a failed push can be continued by Resume. A *CoverError is returned when every track was added
+// but the cover image was not uploaded.
+func (m *Manager) Push(ctx context.Context, name string, songs []string, opts PlaylistOptions, journal Journal) error {
+	if err := opts.Validate(); err != nil {
 		return err
 	}
 
-	if count.Load() == 0 {
-		return errors.New("no tracks found")
+	playlistID, err := m.target.CreatePlaylist(ctx, name, opts)
+
+	var cerr *CoverError
+	if err != nil && (!errors.As(err, &cerr) || playlistID == "") {
+		return fmt.Errorf("%s: create playlist: %w", m.target.Name(), err)
+	}
+
+	p := &Progress{
+		Target:     m.target.Name(),
+		Name:       name,
+		PlaylistID: playlistID,
+		Tracks:     songs,
+	}
+
+	if journal != nil {
+		if err := journal.Save(*p); err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+
+	if err := m.populate(ctx, p, journal); err != nil {
+		return err
+	}
+
+	if cerr != nil {
+		return fmt.Errorf("%s: %w", m.target.Name(), cerr)
 	}
 
 	return nil
 }
 
-func (m *Manager) Push(ctx context.Context, name string, songs []string) error {
-	playlistID, err := m.target.CreatePlaylist(ctx, name)
+// Update adds the given songs missing from an existing playlist, found by ID or name.
+// When replace is true the current playlist tracks are removed first.
+func (m *Manager) Update(ctx context.Context, playlist string, songs []string, replace bool) error {
+	p, err := findPlaylist(ctx, m.target, playlist)
 	if err != nil {
-		return fmt.Errorf("%s: create playlist: %w", m.target.Name(), err)
+		return fmt.Errorf("%s: %w", m.target.Name(), err)
 	}
 
-	if err := m.target.PopulatePlaylist(ctx, playlistID, songs); err != nil {
+	current, err := m.target.PlaylistTracks(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("%s: playlist tracks: %w", m.target.Name(), err)
+	}
+
+	existing := make(map[string]struct{}, len(current))
+	ids := make([]string, 0, len(current))
+	for _, t := range current {
+		if _, ok := existing[t.ID]; !ok {
+			existing[t.ID] = struct{}{}
+			ids = append(ids, t.ID)
+		}
+	}
+
+	position := len(current)
+	if replace && len(ids) != 0 {
+		if err := m.target.RemoveTracks(ctx, p.ID, ids); err != nil {
+			return fmt.Errorf("%s: remove tracks: %w", m.target.Name(), err)
+		}
+		clear(existing)
+		position = 0
+	}
+
+	missing := make([]string, 0, len(songs))
+	for _, s := range songs {
+		if _, ok := existing[s]; !ok {
+			missing = append(missing, s)
+		}
+	}
+
+	if err := m.target.PopulatePlaylist(ctx, p.ID, position, missing); err != nil {
 		return fmt.Errorf("%s: populate playlist: %w", m.target.Name(), err)
 	}
 
 	return nil
 }
+
+func findPlaylist(ctx context.Context, source Source, playlist string) (Playlist, error) {
+	all, err := source.Playlists(ctx)
+	if err != nil {
+		return Playlist{}, fmt.Errorf("playlists: %w", err)
+	}
+
+	var byName []Playlist
+	for _, p := range all {
+		if p.ID == playlist {
+			return p, nil
+		}
+
+		if strings.EqualFold(p.Name, playlist) {
+			byName = append(byName, p)
+		}
+	}
+
+	switch len(byName) {
+	case 0:
+		return Playlist{}, fmt.Errorf("%q: %w", playlist, ErrPlaylistNotFound)
+	case 1:
+		return byName[0], nil
+	default:
+		return Playlist{}, fmt.Errorf("%d playlists named %q, use the playlist ID instead", len(byName), playlist)
+	}
+}
+
+// Export reads the tracks of a source playlist, found by ID or name, as items to be searched on another target.
+func Export(ctx context.Context, source Source, playlist string) (Playlist, []results.Item, error) {
+	if err := source.Setup(ctx); err != nil {
+		return Playlist{}, nil, fmt.Errorf("%s: setup: %w", source.Name(), err)
+	}
+
+	p, err := findPlaylist(ctx, source, playlist)
+	if err != nil {
+		return Playlist{}, nil, fmt.Errorf("%s: %w", source.Name(), err)
+	}
+
+	tracks, err := source.PlaylistTracks(ctx, p.ID)
+	if err != nil {
+		return Playlist{}, nil, fmt.Errorf("%s: playlist tracks: %w", source.Name(), err)
+	}
+
+	if len(tracks) == 0 {
+		return Playlist{}, nil, fmt.Errorf("%s: playlist %q is empty", source.Name(), p.Name)
+	}
+
+	out := make([]results.Item, 0, len(tracks))
+	for _, t := range tracks {
+		out = append(out, results.NewItem(t.Query()).WithISRC(t.ISRC).WithDuration(t.Duration))
+	}
+
+	return p, out, nil
+}
@@ -0,0 +1,509 @@
+package playlists
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTarget struct {
+	playlists []Playlist
+	tracks    map[string][]Track
+	searches  map[string][]Track
+	isrcs     map[string][]Track
+
+	failures map[string][]error // returned by SearchTracks before its matches
+	mu       sync.Mutex
+
+	populateFailures []error // returned by PopulatePlaylist before adding the tracks
+	coverFailure     error   // wrapped in a CoverError by CreatePlaylist
+	created          int
+
+	removed, populated []string
+	positions          []int // of every PopulatePlaylist call
+}
+
+func (ft *fakeTarget) Name() string {
+	return "fake"
+}
+
+// ValidID rejects the Spotify URIs, so legacy locks can belong to another target.
+func (ft *fakeTarget) ValidID(id string) bool {
+	return id != "" && !strings.HasPrefix(id, "spotify:")
+}
+
+func (ft *fakeTarget) Setup(context.Context) error {
+	return nil
+}
+
+func (ft *fakeTarget) SearchTracks(_ context.Context, query string) ([]Track, error) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	if errs := ft.failures[query]; len(errs) != 0 {
+		ft.failures[query] = errs[1:]
+		return nil, errs[0]
+	}
+
+	return ft.searches[query], nil
+}
+
+func (ft *fakeTarget) SearchFields(_ context.Context, fields Fields) ([]Track, error) {
+	return ft.searches[fields.Artist+"|"+fields.Title], nil
+}
+
+func (ft *fakeTarget) SearchISRC(_ context.Context, isrc string) ([]Track, error) {
+	return ft.isrcs[isrc], nil
+}
+
+func (ft *fakeTarget) CreatePlaylist(context.Context, string, PlaylistOptions) (string, error) {
+	ft.created++
+	if ft.coverFailure != nil {
+		return fmt.Sprintf("P%d", ft.created), &CoverError{Err: ft.coverFailure}
+	}
+	return fmt.Sprintf("P%d", ft.created), nil
+}
+
+func (ft *fakeTarget) PopulatePlaylist(_ context.Context, _ string, position int, tracks []string) error {
+	ft.positions = append(ft.positions, position)
+
+	if len(ft.populateFailures) != 0 {
+		err := ft.populateFailures[0]
+		ft.populateFailures = ft.populateFailures[1:]
+
+		var perr *PopulateError
+		if errors.As(err, &perr) {
+			ft.populated = append(ft.populated, tracks[:perr.Added]...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	ft.populated = append(ft.populated, tracks...)
+	return nil
+}
+
+func (ft *fakeTarget) Playlists(context.Context) ([]Playlist, error) {
+	return ft.playlists, nil
+}
+
+func (ft *fakeTarget) PlaylistTracks(_ context.Context, playlistID string) ([]Track, error) {
+	return ft.tracks[playlistID], nil
+}
+
+func (ft *fakeTarget) RemoveTracks(_ context.Context, _ string, tracks []string) error {
+	ft.removed = append(ft.removed, tracks...)
+	return nil
+}
+
+func TestManager_Gather_search(t *testing.T) {
+	target := &fakeTarget{
+		searches: map[string][]Track{
+			"by query":                {{ID: "Q1"}},
+			"unknown isrc":            {{ID: "Q2"}},
+			"no isrc needed":          {{ID: "Q3"}},
+			"Queen|Bohemian Rhapsody": {{ID: "F1"}},
+			"Queen - Unknown":         {{ID: "Q4"}},
+		},
+		isrcs: map[string][]Track{
+			"ISRC1": {{ID: "I1"}},
+		},
+	}
+
+	items := []results.Item{
+		results.NewItem("by query").WithISRC("ISRC1"),
+		results.NewItem("unknown isrc").WithISRC("ISRC2"),
+		results.NewItem("no isrc needed"),
+		results.ParseItem("Queen - Bohemian Rhapsody"),
+		results.ParseItem("Queen - Unknown [Live Aid] (3:45)"),
+	}
+
+	actual := make([]string, len(items))
+	err := NewManager(target, 1).Gather(context.Background(), items, func(i int, _ results.Item, res Result) {
+		assert.Equal(t, Found, res.Status)
+		actual[i] = res.Matches[0].ID
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"I1", "Q2", "Q3", "F1", "Q4"}, actual)
+}
+
+func TestManager_Gather_failures(t *testing.T) {
+	failure := errors.New("_failure")
+
+	table := []struct {
+		name          string
+		failures      map[string][]error
+		expected      []string
+		expectedError string
+	}{
+		{
+			name:     "retried",
+			failures: map[string][]error{"a": {failure, failure}},
+			expected: []string{"found A", "not found", "found C"},
+		},
+		{
+			name:     "failed",
+			failures: map[string][]error{"a": {failure, failure, failure}},
+			expected: []string{"failed fake: searching \"a\": _failure", "not found", "found C"},
+		},
+		{
+			name:          "nothing found",
+			failures:      map[string][]error{"a": {failure, failure, failure}, "c": {failure, failure, failure}},
+			expected:      []string{"failed fake: searching \"a\": _failure", "not found", "failed fake: searching \"c\": _failure"},
+			expectedError: "no tracks found: fake: searching \"a\": _failure",
+		},
+		{
+			name:          "unauthorized",
+			failures:      map[string][]error{"b": {ErrUnauthorized}},
+			expected:      []string{"found A", "", ""},
+			expectedError: "fake: searching \"b\": unauthorized",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			target := &fakeTarget{
+				searches: map[string][]Track{"a": {{ID: "A"}}, "c": {{ID: "C"}}},
+				failures: test.failures,
+			}
+
+			manager := NewManager(target, 1)
+			manager.retryDelay = time.Millisecond
+
+			items := []results.Item{results.NewItem("a"), results.NewItem("b"), results.NewItem("c")}
+
+			actual := make([]string, len(items))
+			err := manager.Gather(context.Background(), items, func(i int, _ results.Item, res Result) {
+				assert.Empty(t, actual[i], "reported once")
+
+				switch res.Status {
+				case Found:
+					actual[i] = "found " + res.Matches[0].ID
+				case Failed:
+					actual[i] = "failed " + res.Err.Error()
+				default:
+					actual[i] = res.Status.String()
+				}
+			})
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestManager_Search(t *testing.T) {
+	target := &fakeTarget{
+		searches: map[string][]Track{
+			"queen bohemian rhapsody": {
+				{ID: "K", Name: "Bohemian Rhapsody (Karaoke Version)", Title: "Bohemian Rhapsody (Karaoke Version)"},
+				{ID: "O", Name: "Queen - Bohemian Rhapsody", Title: "Bohemian Rhapsody", Artists: []string{"Queen"}},
+			},
+		},
+	}
+
+	matches, err := NewManager(target, 1).Search(context.Background(), results.NewItem("queen bohemian rhapsody"))
+	require.NoError(t, err)
+
+	require.Len(t, matches, 2)
+	assert.Equal(t, "O", matches[0].ID)
+	assert.Equal(t, "K", matches[1].ID)
+}
+
+func TestManager_Gather_unavailable(t *testing.T) {
+	target := &fakeTarget{
+		searches: map[string][]Track{
+			"queen bohemian rhapsody": {
+				{ID: "U", Title: "Bohemian Rhapsody", Artists: []string{"Queen"}, Unavailable: true},
+				{ID: "K", Title: "Bohemian Rhapsody (Karaoke Version)"},
+			},
+			"queen innuendo": {
+				{ID: "I", Title: "Innuendo", Artists: []string{"Queen"}, Unavailable: true},
+			},
+		},
+	}
+
+	items := []results.Item{
+		results.NewItem("queen bohemian rhapsody"),
+		results.NewItem("queen innuendo"),
+	}
+
+	table := []struct {
+		policy   string
+		expected [][]string
+	}{
+		{policy: "last", expected: [][]string{{"K", "U"}, {"I"}}},
+		{policy: "drop", expected: [][]string{{"K"}, nil}},
+		{policy: "keep", expected: [][]string{{"U", "K"}, {"I"}}},
+	}
+	for _, test := range table {
+		t.Run(test.policy, func(t *testing.T) {
+			policy, err := ParseAvailability(test.policy)
+			require.NoError(t, err)
+
+			manager := NewManager(target, 1)
+			manager.SetUnavailable(policy)
+
+			actual := make([][]string, len(items))
+			err = manager.Gather(context.Background(), items, func(i int, _ results.Item, res Result) {
+				for _, m := range res.Matches {
+					actual[i] = append(actual[i], m.ID)
+				}
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestManager_Gather_locked(t *testing.T) {
+	target := &fakeTarget{
+		searches: map[string][]Track{
+			"other target": {{ID: "S1"}},
+			"wrong legacy": {{ID: "S2"}},
+		},
+	}
+
+	items := []results.Item{
+		results.ParseItem(">>LOCKED§L1§_name§legacy"),
+		results.ParseItem(">>LOCKED§spotify:track:L4§_name§wrong legacy"),
+		results.NewItem("same target").WithID("L2").WithName("_name").WithTarget("fake").WithActive(true),
+		results.NewItem("other target").WithID("L3").WithName("_name").WithTarget("other").WithActive(true),
+	}
+
+	actual := make([]string, len(items))
+	err := NewManager(target, 1).Gather(context.Background(), items, func(i int, item results.Item, res Result) {
+		assert.Equal(t, Found, res.Status)
+		if len(res.Matches) == 0 {
+			actual[i] = "locked"
+			return
+		}
+		assert.False(t, item.Active())
+		assert.Equal(t, "fake", item.Target())
+		actual[i] = res.Matches[0].ID
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"locked", "S2", "locked", "S1"}, actual)
+}
+
+type memoryJournal struct {
+	progress *Progress
+	saves    int
+}
+
+func (mj *memoryJournal) Load() (Progress, bool, error) {
+	if mj.progress == nil {
+		return Progress{}, false, nil
+	}
+	return *mj.progress, true, nil
+}
+
+func (mj *memoryJournal) Save(p Progress) error {
+	mj.saves++
+	mj.progress = &p
+	return nil
+}
+
+func (mj *memoryJournal) Done() error {
+	mj.progress = nil
+	return nil
+}
+
+func TestManager_Push_resume(t *testing.T) {
+	songs := make([]string, 250)
+	for i := range songs {
+		songs[i] = fmt.Sprintf("S%03d", i)
+	}
+
+	target := &fakeTarget{
+		populateFailures: []error{nil, &PopulateError{Added: 30, Err: errors.New("_failure")}},
+	}
+
+	journal := &memoryJournal{}
+	manager := NewManager(target, 1)
+
+	err := manager.Resume(context.Background(), journal)
+	require.ErrorIs(t, err, ErrNothingToResume)
+
+	err = manager.Push(context.Background(), "_NAME", songs, PlaylistOptions{}, journal)
+	require.EqualError(t, err, "fake: populate playlist: 130 tracks already added: _failure")
+
+	var perr *PopulateError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, 130, perr.Added)
+
+	require.NotNil(t, journal.progress)
+	assert.Equal(t, Progress{Target: "fake", Name: "_NAME", PlaylistID: "P1", Tracks: songs, Added: 130}, *journal.progress)
+
+	require.NoError(t, manager.Resume(context.Background(), journal))
+
+	assert.Equal(t, 1, target.created, "the same playlist is used")
+	assert.Equal(t, songs, target.populated, "no track is added twice")
+	assert.Equal(t, []int{0, 100, 130, 230}, target.positions, "each batch follows the tracks already added")
+	assert.Nil(t, journal.progress, "done")
+
+	err = NewManager(&otherTarget{target}, 1).Resume(context.Background(), &memoryJournal{progress: &Progress{Target: "fake", Name: "_NAME"}})
+	require.EqualError(t, err, `journal: playlist "_NAME" was pushed to fake`)
+}
+
+func TestManager_Push_coverError(t *testing.T) {
+	target := &fakeTarget{coverFailure: errors.New("_failure")}
+	journal := &memoryJournal{}
+
+	err := NewManager(target, 1).Push(context.Background(), "_NAME", []string{"S1", "S2"}, PlaylistOptions{}, journal)
+	require.EqualError(t, err, "fake: cover not uploaded: _failure")
+
+	var cerr *CoverError
+	require.ErrorAs(t, err, &cerr)
+
+	assert.Equal(t, []string{"S1", "S2"}, target.populated, "the tracks are added anyway")
+	assert.Equal(t, 2, journal.saves, "the playlist is recorded")
+	assert.Nil(t, journal.progress, "done")
+}
+
+type otherTarget struct {
+	*fakeTarget
+}
+
+func (ot *otherTarget) Name() string {
+	return "other"
+}
+
+func TestManager_Update(t *testing.T) {
+	table := []struct {
+		name              string
+		playlist          string
+		replace           bool
+		expectedRemoved   []string
+		expectedPopulated []string
+		expectedPositions []int
+		expectedError     string
+	}{
+		{
+			name:              "append by name",
+			playlist:          "friday PARTY",
+			expectedPopulated: []string{"C", "D"},
+			expectedPositions: []int{3},
+		},
+		{
+			name:              "append by ID",
+			playlist:          "p1",
+			expectedPopulated: []string{"C", "D"},
+			expectedPositions: []int{3},
+		},
+		{
+			name:              "replace",
+			playlist:          "p1",
+			replace:           true,
+			expectedRemoved:   []string{"A", "B"},
+			expectedPopulated: []string{"A", "C", "D"},
+			expectedPositions: []int{0},
+		},
+		{
+			name:          "not found",
+			playlist:      "nope",
+			expectedError: `fake: "nope": playlist not found`,
+		},
+		{
+			name:          "ambiguous name",
+			playlist:      "duplicated",
+			expectedError: `fake: 2 playlists named "duplicated", use the playlist ID instead`,
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			target := &fakeTarget{
+				playlists: []Playlist{
+					{ID: "p1", Name: "Friday party"},
+					{ID: "p2", Name: "duplicated"},
+					{ID: "p3", Name: "duplicated"},
+				},
+				tracks: map[string][]Track{
+					"p1": {{ID: "A"}, {ID: "B"}, {ID: "A"}},
+				},
+			}
+
+			err := NewManager(target, 1).Update(context.Background(), test.playlist, []string{"A", "C", "D"}, test.replace)
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			assert.Equal(t, test.expectedRemoved, target.removed)
+			assert.Equal(t, test.expectedPopulated, target.populated)
+			assert.Equal(t, test.expectedPositions, target.positions)
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	source := &fakeTarget{
+		playlists: []Playlist{{ID: "p1", Name: "Friday party"}},
+		tracks: map[string][]Track{
+			"p1": {
+				{ID: "A", Name: "Artist A - Title A <Album>", Title: "Title A", Artists: []string{"Artist A"}, ISRC: "ISRC_A"},
+				{ID: "B", Name: "Artists B - Title B", Title: "Title B", Artists: []string{"Artist B1", "Artist B2"}},
+				{ID: "C", Name: "Only name"},
+			},
+		},
+	}
+
+	playlist, items, err := Export(context.Background(), source, "Friday party")
+	require.NoError(t, err)
+
+	assert.Equal(t, Playlist{ID: "p1", Name: "Friday party"}, playlist)
+
+	queries := make([]string, 0, len(items))
+	for _, item := range items {
+		assert.False(t, item.Active())
+		queries = append(queries, item.Query())
+	}
+	assert.Equal(t, []string{"Artist A Title A", "Artist B1 Artist B2 Title B", "Only name"}, queries)
+	assert.Equal(t, "ISRC_A", items[0].ISRC())
+	assert.Empty(t, items[1].ISRC())
+}
+
+func TestPlaylistOptions_Validate(t *testing.T) {
+	table := []struct {
+		name          string
+		opts          PlaylistOptions
+		expectedError string
+	}{
+		{
+			name: "zero",
+		},
+		{
+			name: "public with cover",
+			opts: PlaylistOptions{Public: true, Description: "description", Cover: []byte{0xFF, 0xD8, 0xFF, 0xE0}},
+		},
+		{
+			name:          "public collaborative",
+			opts:          PlaylistOptions{Public: true, Collaborative: true},
+			expectedError: "collaborative playlists can't be public",
+		},
+		{
+			name:          "not a JPEG",
+			opts:          PlaylistOptions{Cover: []byte("\x89PNG")},
+			expectedError: "cover image is not a JPEG",
+		},
+		{
+			name:          "too big",
+			opts:          PlaylistOptions{Cover: append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, maxCoverSize)...)},
+			expectedError: "cover image bigger than 190 KB",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedError, tests.AsString(test.opts.Validate()))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package playlists
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+)
+
+// Match is a candidate track scored against the searched item, from 0 (unrelated) to 1 (exact).
+type Match struct {
+	Track
+	Score float64
+}
+
+// Availability tells what to do with the matches the target can't play.
+type Availability int
+
+const (
+	Demote Availability = iota // rank them after every playable match
+	Drop                       // leave them out
+	Keep                       // rank them as any other match
+)
+
+var availabilities = map[string]Availability{"last": Demote, "drop": Drop, "keep": Keep}
+
+// ParseAvailability parses one of last, drop or keep.
+func ParseAvailability(v string) (Availability, error) {
+	out, ok := availabilities[v]
+	if !ok {
+		return 0, fmt.Errorf("invalid unavailable policy %q, use last, drop or keep", v)
+	}
+
+	return out, nil
+}
+
+// versionWords mark alternative versions in track titles, penalized unless the query asks for them, and the other way around.
+var versionWords = []string{"live", "remix", "remixed", "karaoke", "instrumental", "cover", "tribute", "acoustic", "demo"}
+
+const (
+	versionPenalty    = 0.35
+	durationTolerance = 3 * time.Second
+	durationPenalty   = 0.3
+	durationMaxDiff   = 30 * time.Second
+)
+
+// Rank scores the candidates against the item and sorts them, best first.
+func Rank(item results.Item, tracks []Track) []Match {
+	out := make([]Match, 0, len(tracks))
+	for _, t := range tracks {
+		out = append(out, Match{Track: t, Score: score(item, t)})
+	}
+
+	slices.SortStableFunc(out, func(a, b Match) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return out
+}
+
+func score(item results.Item, t Track) float64 {
+	text := item.Query()
+	if item.Title() != "" {
+		text = item.Artist() + " " + item.Title()
+	}
+
+	query := words(text)
+	if len(query) == 0 {
+		return 0
+	}
+
+	title, artists := words(t.Title), words(strings.Join(t.Artists, " "))
+	if len(title) == 0 {
+		title = words(t.Name)
+	}
+	all := append(append(append([]string{}, title...), artists...), words(t.Album)...)
+
+	var out float64
+	if len(artists) == 0 {
+		out = 0.6*coverage(title, query) + 0.4*coverage(query, all)
+	} else {
+		out = 0.4*coverage(title, query) + 0.3*coverage(artists, query) + 0.3*coverage(query, all)
+	}
+
+	// only the title tells the version, the name may hold the album as well
+	for _, w := range versionWords {
+		if slices.Contains(title, w) != slices.Contains(query, w) {
+			out -= versionPenalty
+			break
+		}
+	}
+
+	if expected := item.Duration(); expected != 0 && t.Duration != 0 {
+		diff := (expected - t.Duration).Abs()
+		if diff > durationTolerance {
+			out -= durationPenalty * float64(min(diff, durationMaxDiff)) / float64(durationMaxDiff)
+		}
+	}
+
+	return max(0, min(1, out))
+}
+
+// coverage returns the fraction of the words found in the target.
+func coverage(words, target []string) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	var found int
+	for _, w := range words {
+		if slices.Contains(target, w) {
+			found++
+		}
+	}
+
+	return float64(found) / float64(len(words))
+}
+
+// words normalizes the text into lower case alphanumeric words, ignoring punctuation.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
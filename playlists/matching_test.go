@@ -0,0 +1,96 @@
+package playlists
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/results"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRank(t *testing.T) {
+	table := []struct {
+		name     string
+		item     results.Item
+		tracks   []Track
+		expected []string
+	}{
+		{
+			name: "original over karaoke",
+			item: results.NewItem("Queen - Bohemian Rhapsody"),
+			tracks: []Track{
+				{ID: "karaoke", Title: "Bohemian Rhapsody (Karaoke Version)", Artists: []string{"Karaoke Hits"}},
+				{ID: "original", Title: "Bohemian Rhapsody", Artists: []string{"Queen"}, Album: "A Night at the Opera"},
+				{ID: "live", Title: "Bohemian Rhapsody - Live Aid", Artists: []string{"Queen"}},
+			},
+			expected: []string{"original", "live", "karaoke"},
+		},
+		{
+			name: "live requested",
+			item: results.NewItem("queen bohemian rhapsody live"),
+			tracks: []Track{
+				{ID: "original", Title: "Bohemian Rhapsody", Artists: []string{"Queen"}},
+				{ID: "live", Title: "Bohemian Rhapsody - Live Aid", Artists: []string{"Queen"}},
+			},
+			expected: []string{"live", "original"},
+		},
+		{
+			name: "duration",
+			item: results.NewItem("the clash mustapha dance").WithDuration(265 * time.Second),
+			tracks: []Track{
+				{ID: "edit", Title: "Mustapha Dance", Artists: []string{"The Clash"}, Duration: 200 * time.Second},
+				{ID: "album", Title: "Mustapha Dance", Artists: []string{"The Clash"}, Duration: 266 * time.Second},
+			},
+			expected: []string{"album", "edit"},
+		},
+		{
+			name: "remastered",
+			item: results.NewItem("queen bohemian rhapsody"),
+			tracks: []Track{
+				{ID: "live", Title: "Bohemian Rhapsody - Live Aid", Artists: []string{"Queen"}},
+				{ID: "remastered", Title: "Bohemian Rhapsody - Remastered 2011", Artists: []string{"Queen"}},
+			},
+			expected: []string{"remastered", "live"},
+		},
+		{
+			name: "live album",
+			item: results.NewItem("queen bohemian rhapsody"),
+			tracks: []Track{
+				{ID: "featuring", Title: "Bohemian Rhapsody", Artists: []string{"Queen", "Freddie Mercury"}},
+				{ID: "album", Name: "Queen - Bohemian Rhapsody <Live Killers>", Title: "Bohemian Rhapsody", Artists: []string{"Queen"}, Album: "Live Killers"},
+			},
+			expected: []string{"album", "featuring"},
+		},
+		{
+			name: "name only",
+			item: results.NewItem("tahitian moon"),
+			tracks: []Track{
+				{ID: "other", Name: "Porno For Pyros - Pets"},
+				{ID: "match", Name: "Porno For Pyros - Tahitian Moon"},
+			},
+			expected: []string{"match", "other"},
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			matches := Rank(test.item, test.tracks)
+
+			actual := make([]string, 0, len(matches))
+			for _, m := range matches {
+				assert.GreaterOrEqual(t, m.Score, 0.0)
+				assert.LessOrEqual(t, m.Score, 1.0)
+				actual = append(actual, m.ID)
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestRank_exact(t *testing.T) {
+	matches := Rank(results.NewItem("The Clash - Mustapha Dance"), []Track{
+		{Title: "Mustapha Dance", Artists: []string{"The Clash"}},
+	})
+
+	assert.InDelta(t, 1.0, matches[0].Score, 0.001)
+}
@@ -0,0 +1,312 @@
+// Package playliststest provides a conformance suite checking a playlists.Target behaves as the playlists package expects.
+package playliststest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agukrapo/playlist-creator/playlists"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// missingPlaylist is the ID of a playlist no target has.
+const missingPlaylist = "_MISSING"
+
+// Suite describes the target under test and the data it serves.
+type Suite struct {
+	// New builds a target, a fresh one for every check.
+	New func(t *testing.T) playlists.Target
+	// Unauthorized builds a target whose credentials are rejected, the check is skipped when nil.
+	Unauthorized func(t *testing.T) playlists.Target
+	// Throttled builds a target whose service rate limits its first requests, the check is skipped when nil.
+	Throttled func(t *testing.T) playlists.Target
+	// Failing builds a target whose service fails adding tracks after the first request, the check is skipped when nil.
+	Failing func(t *testing.T) playlists.Target
+
+	Query  string           // free-text query with matches
+	ISRC   string           // ISRC of a track, empty to skip the check
+	Fields playlists.Fields // fields with matches
+	Tracks []string         // IDs of at least two tracks that can be added to a playlist
+}
+
+// Run runs every check as a subtest.
+func (s Suite) Run(t *testing.T) {
+	t.Helper()
+
+	require.NotNil(t, s.New, "Suite.New")
+	require.GreaterOrEqual(t, len(s.Tracks), 2, "Suite.Tracks")
+
+	t.Run("Name", s.name)
+	t.Run("Setup", s.setup)
+	t.Run("ValidID", s.validID)
+	t.Run("Search", s.search)
+	t.Run("Playlist", s.playlist)
+	t.Run("Order", s.order)
+	t.Run("EmptyInputs", s.emptyInputs)
+	t.Run("NotFound", s.notFound)
+	t.Run("Unauthorized", s.unauthorized)
+	t.Run("Throttled", s.throttled)
+	t.Run("PopulateError", s.populateError)
+	t.Run("Canceled", s.canceled)
+}
+
+// target builds a target and sets it up.
+func (s Suite) target(t *testing.T) playlists.Target {
+	t.Helper()
+
+	target := s.New(t)
+	require.NoError(t, target.Setup(context.Background()))
+
+	return target
+}
+
+func (s Suite) name(t *testing.T) {
+	target := s.New(t)
+
+	assert.NotEmpty(t, target.Name())
+	assert.Equal(t, target.Name(), target.Name())
+}
+
+func (s Suite) setup(t *testing.T) {
+	target := s.target(t)
+
+	assert.NoError(t, target.Setup(context.Background()), "setting up again")
+}
+
+func (s Suite) validID(t *testing.T) {
+	target := s.New(t)
+
+	for _, id := range s.Tracks {
+		assert.True(t, target.ValidID(id), id)
+	}
+	assert.False(t, target.ValidID(""))
+}
+
+func (s Suite) search(t *testing.T) {
+	target := s.target(t)
+	ctx := context.Background()
+
+	matches, err := target.SearchTracks(ctx, s.Query)
+	require.NoError(t, err)
+	require.NotEmpty(t, matches, "query %q", s.Query)
+	for _, m := range matches {
+		assert.NotEmpty(t, m.ID)
+		assert.NotEmpty(t, m.Name)
+	}
+
+	matches, err = target.SearchFields(ctx, s.Fields)
+	require.NoError(t, err)
+	assert.NotEmpty(t, matches, "fields %+v", s.Fields)
+
+	if s.ISRC == "" {
+		return
+	}
+
+	matches, err = target.SearchISRC(ctx, s.ISRC)
+	require.NoError(t, err)
+	require.NotEmpty(t, matches, "ISRC %s", s.ISRC)
+	for _, m := range matches {
+		assert.True(t, strings.EqualFold(s.ISRC, m.ISRC), "ISRC %s matched %s", s.ISRC, m.ISRC)
+	}
+}
+
+func (s Suite) playlist(t *testing.T) {
+	target := s.target(t)
+	ctx := context.Background()
+
+	id, err := target.CreatePlaylist(ctx, "_NAME", playlists.PlaylistOptions{Description: "_DESCRIPTION"})
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	require.NoError(t, target.PopulatePlaylist(ctx, id, 0, s.Tracks))
+	assert.Equal(t, s.Tracks, trackIDs(t, target, id))
+
+	list, err := target.Playlists(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, list, playlists.Playlist{ID: id, Name: "_NAME"})
+
+	require.NoError(t, target.RemoveTracks(ctx, id, s.Tracks[:1]))
+	assert.Equal(t, s.Tracks[1:], trackIDs(t, target, id))
+}
+
+// order checks tracks added over several calls, as the Manager does in batches, keep their order past the first batch.
+func (s Suite) order(t *testing.T) {
+	target := s.target(t)
+	ctx := context.Background()
+
+	id, err := target.CreatePlaylist(ctx, "_NAME", playlists.PlaylistOptions{})
+	require.NoError(t, err)
+
+	tracks := cycle(s.Tracks, 250)
+
+	require.NoError(t, target.PopulatePlaylist(ctx, id, 0, tracks[:101]))
+	require.NoError(t, target.PopulatePlaylist(ctx, id, 101, tracks[101:]))
+	assert.Equal(t, tracks, trackIDs(t, target, id))
+}
+
+func (s Suite) emptyInputs(t *testing.T) {
+	target := s.target(t)
+	ctx := context.Background()
+
+	matches, err := target.SearchTracks(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, matches, "empty query")
+
+	matches, err = target.SearchISRC(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, matches, "empty ISRC")
+
+	matches, err = target.SearchFields(ctx, playlists.Fields{})
+	require.NoError(t, err)
+	assert.Empty(t, matches, "empty fields")
+
+	id, err := target.CreatePlaylist(ctx, "_NAME", playlists.PlaylistOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, target.PopulatePlaylist(ctx, id, 0, nil))
+	require.NoError(t, target.RemoveTracks(ctx, id, nil))
+	assert.Empty(t, trackIDs(t, target, id))
+}
+
+// notFound checks the playlist operations report a missing playlist with ErrPlaylistNotFound.
+func (s Suite) notFound(t *testing.T) {
+	target := s.target(t)
+	ctx := context.Background()
+
+	_, err := target.PlaylistTracks(ctx, missingPlaylist)
+	assert.ErrorIs(t, err, playlists.ErrPlaylistNotFound, "PlaylistTracks")
+
+	err = target.PopulatePlaylist(ctx, missingPlaylist, 0, s.Tracks)
+	assert.ErrorIs(t, err, playlists.ErrPlaylistNotFound, "PopulatePlaylist")
+
+	err = target.RemoveTracks(ctx, missingPlaylist, s.Tracks)
+	assert.ErrorIs(t, err, playlists.ErrPlaylistNotFound, "RemoveTracks")
+}
+
+// unauthorized checks rejected credentials are reported by Setup, or by the first search for targets checking them lazily.
+func (s Suite) unauthorized(t *testing.T) {
+	if s.Unauthorized == nil {
+		t.Skip("the target has no credentials")
+	}
+
+	target := s.Unauthorized(t)
+	ctx := context.Background()
+
+	err := target.Setup(ctx)
+	if err == nil {
+		_, err = target.SearchTracks(ctx, s.Query)
+	}
+
+	require.ErrorIs(t, err, playlists.ErrUnauthorized)
+}
+
+// throttled checks rate limited requests are retried.
+func (s Suite) throttled(t *testing.T) {
+	if s.Throttled == nil {
+		t.Skip("the target service has no rate limit")
+	}
+
+	target := s.Throttled(t)
+	ctx := context.Background()
+
+	require.NoError(t, target.Setup(ctx))
+
+	matches, err := target.SearchTracks(ctx, s.Query)
+	require.NoError(t, err)
+	assert.NotEmpty(t, matches)
+}
+
+// populateError checks a PopulatePlaylist failing halfway tells how many tracks were added.
+func (s Suite) populateError(t *testing.T) {
+	if s.Failing == nil {
+		t.Skip("the target adds every track at once")
+	}
+
+	target := s.Failing(t)
+	ctx := context.Background()
+	require.NoError(t, target.Setup(ctx))
+
+	id, err := target.CreatePlaylist(ctx, "_NAME", playlists.PlaylistOptions{})
+	require.NoError(t, err)
+
+	tracks := cycle(s.Tracks, 250)
+
+	err = target.PopulatePlaylist(ctx, id, 0, tracks)
+
+	var perr *playlists.PopulateError
+	require.ErrorAs(t, err, &perr)
+	assert.Positive(t, perr.Added)
+	assert.Less(t, perr.Added, len(tracks))
+	assert.Len(t, trackIDs(t, target, id), perr.Added)
+}
+
+func (s Suite) canceled(t *testing.T) {
+	target := s.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	table := map[string]func() error{
+		"SearchTracks": func() error {
+			_, err := target.SearchTracks(ctx, s.Query)
+			return err
+		},
+		"SearchFields": func() error {
+			_, err := target.SearchFields(ctx, s.Fields)
+			return err
+		},
+		"CreatePlaylist": func() error {
+			_, err := target.CreatePlaylist(ctx, "_NAME", playlists.PlaylistOptions{})
+			return err
+		},
+		"PopulatePlaylist": func() error {
+			return target.PopulatePlaylist(ctx, "_PLAYLIST", 0, s.Tracks)
+		},
+		"Playlists": func() error {
+			_, err := target.Playlists(ctx)
+			return err
+		},
+		"PlaylistTracks": func() error {
+			_, err := target.PlaylistTracks(ctx, "_PLAYLIST")
+			return err
+		},
+	}
+	if s.ISRC != "" {
+		table["SearchISRC"] = func() error {
+			_, err := target.SearchISRC(ctx, s.ISRC)
+			return err
+		}
+	}
+
+	for name, call := range table {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, call(), context.Canceled)
+		})
+	}
+}
+
+func trackIDs(t *testing.T, target playlists.Target, playlistID string) []string {
+	t.Helper()
+
+	tracks, err := target.PlaylistTracks(context.Background(), playlistID)
+	require.NoError(t, err)
+
+	out := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		out = append(out, track.ID)
+	}
+
+	return out
+}
+
+// cycle returns n track IDs repeating the given ones in order.
+func cycle(tracks []string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = tracks[i%len(tracks)]
+	}
+
+	return out
+}
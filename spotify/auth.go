@@ -0,0 +1,334 @@
+package spotify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/go-http-client/requests"
+	"github.com/agukrapo/playlist-creator/internal/credentials"
+	"github.com/agukrapo/playlist-creator/internal/random"
+)
+
+// ErrNotLoggedIn is returned when there is no stored token to authenticate with.
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// Scopes requested by the login flow.
+var Scopes = []string{
+	"playlist-read-private",
+	"playlist-modify-private",
+	"playlist-modify-public",
+	"ugc-image-upload",
+}
+
+// Token holds the OAuth tokens of a logged user.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+func (t *Token) valid() bool {
+	return t != nil && t.AccessToken != "" && time.Now().Before(t.Expiry)
+}
+
+// TokenStore persists tokens between runs.
+type TokenStore interface {
+	Load() (*Token, error)
+	Save(*Token) error
+}
+
+type credentialsStore struct {
+	backend credentials.Backend
+}
+
+// NewTokenStore creates a TokenStore that keeps the token in the given credentials backend.
+func NewTokenStore(backend credentials.Backend) TokenStore {
+	return credentialsStore{backend: backend}
+}
+
+// Load reads the stored token, returns ErrNotLoggedIn if there is none.
+func (cs credentialsStore) Load() (*Token, error) {
+	v, err := cs.backend.Get("spotify", "oauth")
+	if errors.Is(err, credentials.ErrNotFound) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out Token
+	if err := json.Unmarshal([]byte(v), &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func (cs credentialsStore) Save(token *Token) error {
+	bytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return cs.backend.Set("spotify", "oauth", string(bytes))
+}
+
+// Authenticator implements the OAuth authorization code flow with PKCE.
+type Authenticator struct {
+	httpClient   doer
+	clientID     string
+	redirectURI  string
+	authorizeURL string
+	tokenURL     string
+
+	store TokenStore
+	token *Token
+	mu    sync.Mutex
+}
+
+// AuthOption configures an Authenticator.
+type AuthOption func(*Authenticator)
+
+// TokenURL overrides the token endpoint.
+func TokenURL(u string) AuthOption {
+	return func(a *Authenticator) {
+		a.tokenURL = u
+	}
+}
+
+// AuthorizeURL overrides the authorization endpoint.
+func AuthorizeURL(u string) AuthOption {
+	return func(a *Authenticator) {
+		a.authorizeURL = u
+	}
+}
+
+// NewAuthenticator creates a new Authenticator for the given application client ID.
+// The redirect URI must be a loopback address registered in the Spotify application.
+func NewAuthenticator(httpClient doer, clientID, redirectURI string, store TokenStore, opts ...AuthOption) *Authenticator {
+	out := &Authenticator{
+		httpClient:   httpClient,
+		clientID:     clientID,
+		redirectURI:  redirectURI,
+		authorizeURL: "https://accounts.spotify.com/authorize",
+		tokenURL:     "https://accounts.spotify.com/api/token",
+		store:        store,
+	}
+
+	for _, o := range opts {
+		o(out)
+	}
+
+	return out
+}
+
+// Login runs the authorization flow: it listens on the redirect URI, calls open with the URL
+// the user must visit and exchanges the received code for a token.
+func (a *Authenticator) Login(ctx context.Context, open func(authURL string) error) error {
+	redirect, err := url.Parse(a.redirectURI)
+	if err != nil {
+		return err
+	}
+
+	lc := net.ListenConfig{}
+	listener, err := lc.Listen(ctx, "tcp", redirect.Host)
+	if err != nil {
+		return err
+	}
+	redirect.Host = listener.Addr().String()
+
+	verifier := random.Name(64)
+	state := random.Name(16)
+
+	callbacks := make(chan url.Values, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirect.Path, func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+
+		if q.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case callbacks <- q:
+		default:
+		}
+
+		_, _ = fmt.Fprintln(w, "Done, you can close this window.")
+	})
+
+	svr := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = svr.Serve(listener) }()
+	defer svr.Close()
+
+	if err := open(a.authCodeURL(redirect.String(), state, challenge(verifier))); err != nil {
+		return err
+	}
+
+	var q url.Values
+	select {
+	case q = <-callbacks:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if v := q.Get("error"); v != "" {
+		return fmt.Errorf("authorization denied: %s", v)
+	}
+
+	code := q.Get("code")
+
+	vs := url.Values{}
+	vs.Set("grant_type", "authorization_code")
+	vs.Set("code", code)
+	vs.Set("redirect_uri", redirect.String())
+	vs.Set("client_id", a.clientID)
+	vs.Set("code_verifier", verifier)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.requestToken(ctx, vs, "")
+}
+
+func (a *Authenticator) authCodeURL(redirectURI, state, challenge string) string {
+	vs := url.Values{}
+	vs.Set("client_id", a.clientID)
+	vs.Set("response_type", "code")
+	vs.Set("redirect_uri", redirectURI)
+	vs.Set("state", state)
+	vs.Set("scope", strings.Join(Scopes, " "))
+	vs.Set("code_challenge_method", "S256")
+	vs.Set("code_challenge", challenge)
+
+	return a.authorizeURL + "?" + vs.Encode()
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Token returns a valid access token, loading it from the store and refreshing it when expired.
+func (a *Authenticator) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(); err != nil {
+		return "", err
+	}
+
+	if a.token.valid() {
+		return a.token.AccessToken, nil
+	}
+
+	if err := a.refresh(ctx); err != nil {
+		return "", err
+	}
+
+	return a.token.AccessToken, nil
+}
+
+// Refresh obtains a new access token using the stored refresh token.
+func (a *Authenticator) Refresh(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(); err != nil {
+		return "", err
+	}
+
+	if err := a.refresh(ctx); err != nil {
+		return "", err
+	}
+
+	return a.token.AccessToken, nil
+}
+
+func (a *Authenticator) load() error {
+	if a.token != nil {
+		return nil
+	}
+
+	token, err := a.store.Load()
+	if err != nil {
+		return err
+	}
+
+	a.token = token
+
+	return nil
+}
+
+func (a *Authenticator) refresh(ctx context.Context) error {
+	if a.token.RefreshToken == "" {
+		return ErrNotLoggedIn
+	}
+
+	vs := url.Values{}
+	vs.Set("grant_type", "refresh_token")
+	vs.Set("refresh_token", a.token.RefreshToken)
+	vs.Set("client_id", a.clientID)
+
+	return a.requestToken(ctx, vs, a.token.RefreshToken)
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (a *Authenticator) requestToken(ctx context.Context, vs url.Values, refreshToken string) error {
+	req, err := requests.New(a.tokenURL).Post().
+		Header("Content-Type", "application/x-www-form-urlencoded").
+		Body(strings.NewReader(vs.Encode())).
+		Build(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var out tokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		if out.ErrorDescription != "" {
+			return fmt.Errorf("%s: %s", out.Error, out.ErrorDescription)
+		}
+		return fmt.Errorf("%s: %s", res.Status, out.Error)
+	}
+
+	if out.RefreshToken != "" {
+		refreshToken = out.RefreshToken
+	}
+
+	a.token = &Token{
+		AccessToken:  out.AccessToken,
+		RefreshToken: refreshToken,
+		Expiry:       time.Now().Add(time.Duration(out.ExpiresIn) * time.Second),
+	}
+
+	return a.store.Save(a.token)
+}
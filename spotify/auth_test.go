@@ -0,0 +1,132 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/playlist-creator/internal/credentials"
+	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Login(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+		require.NoError(t, req.ParseForm())
+		assert.Equal(t, "authorization_code", req.PostForm.Get("grant_type"))
+		assert.Equal(t, "_CODE", req.PostForm.Get("code"))
+		assert.Equal(t, "_CLIENT_ID", req.PostForm.Get("client_id"))
+		assert.Len(t, req.PostForm.Get("code_verifier"), 64)
+
+		_, err := w.Write([]byte(`{"access_token":"_ACCESS","refresh_token":"_REFRESH","expires_in":3600}`))
+		assert.NoError(t, err)
+	}))
+	defer svr.Close()
+
+	store := NewTokenStore(credentials.NewFile(filepath.Join(t.TempDir(), "credentials.json")))
+	auth := NewAuthenticator(http.DefaultClient, "_CLIENT_ID", "http://127.0.0.1:0/callback", store, TokenURL(svr.URL))
+
+	err := auth.Login(context.Background(), func(authURL string) error {
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		q := u.Query()
+		assert.Equal(t, "_CLIENT_ID", q.Get("client_id"))
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.NotEmpty(t, q.Get("code_challenge"))
+
+		go func() {
+			res, err := http.Get(q.Get("redirect_uri") + "?code=_CODE&state=" + q.Get("state"))
+			if assert.NoError(t, err) {
+				_ = res.Body.Close()
+			}
+		}()
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	token, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "_ACCESS", token.AccessToken)
+	assert.Equal(t, "_REFRESH", token.RefreshToken)
+	assert.True(t, token.valid())
+}
+
+func TestAuthenticator_Token(t *testing.T) {
+	table := []struct {
+		name           string
+		stored         *Token
+		responseStatus int
+		responseBody   string
+		expected       string
+		expectedError  string
+	}{
+		{
+			name:     "valid",
+			stored:   &Token{AccessToken: "_STORED", RefreshToken: "_REFRESH", Expiry: time.Now().Add(time.Hour)},
+			expected: "_STORED",
+		},
+		{
+			name:           "expired",
+			stored:         &Token{AccessToken: "_STORED", RefreshToken: "_REFRESH", Expiry: time.Now().Add(-time.Hour)},
+			responseStatus: http.StatusOK,
+			responseBody:   `{"access_token":"_ACCESS","expires_in":3600}`,
+			expected:       "_ACCESS",
+		},
+		{
+			name:           "refresh error",
+			stored:         &Token{AccessToken: "_STORED", RefreshToken: "_REFRESH", Expiry: time.Now().Add(-time.Hour)},
+			responseStatus: http.StatusBadRequest,
+			responseBody:   `{"error":"invalid_grant","error_description":"Refresh token revoked"}`,
+			expectedError:  "invalid_grant: Refresh token revoked",
+		},
+		{
+			name:          "not logged in",
+			expectedError: "not logged in",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				require.NoError(t, req.ParseForm())
+				assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+				assert.Equal(t, "_REFRESH", req.PostForm.Get("refresh_token"))
+				assert.Equal(t, "_CLIENT_ID", req.PostForm.Get("client_id"))
+
+				w.WriteHeader(test.responseStatus)
+				_, err := w.Write([]byte(test.responseBody))
+				assert.NoError(t, err)
+			}))
+			defer svr.Close()
+
+			store := NewTokenStore(credentials.NewFile(filepath.Join(t.TempDir(), "credentials.json")))
+			if test.stored != nil {
+				require.NoError(t, store.Save(test.stored))
+			}
+
+			auth := NewAuthenticator(http.DefaultClient, "_CLIENT_ID", "", store, TokenURL(svr.URL))
+
+			token, err := auth.Token(context.Background())
+			require.Equal(t, test.expectedError, tests.AsString(err))
+			assert.Equal(t, test.expected, token)
+
+			if test.expected == "" {
+				return
+			}
+
+			saved, err := store.Load()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, saved.AccessToken)
+			assert.Equal(t, "_REFRESH", saved.RefreshToken)
+		})
+	}
+}
@@ -1,14 +1,20 @@
 package spotify
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/agukrapo/go-http-client/requests"
 	"github.com/agukrapo/playlist-creator/playlists"
@@ -18,12 +24,29 @@ type doer interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+type authenticator interface {
+	Token(ctx context.Context) (string, error)
+	Refresh(ctx context.Context) (string, error)
+}
+
+// Search depth bounds: the search endpoint returns up to 50 results per page, and up to offset 1000.
+const (
+	maxSearchLimit = 50
+	maxSearchDepth = 1000
+)
+
 // Client represents a Spotify client.
 type Client struct {
 	httpClient doer
 	baseURL    string
 	token      string
 	userID     string
+
+	depth  int    // maximum search results, a single page when zero
+	market string // search market, empty for any
+
+	auth authenticator
+	mu   sync.RWMutex
 }
 
 // New creates a new Client.
@@ -35,9 +58,35 @@ func New(httpClient doer, token string) *Client {
 	}
 }
 
+// SetSearchDepth sets the maximum number of results of every search, up to 1000, fetched in pages of 50.
+func (c *Client) SetSearchDepth(depth int) {
+	c.depth = min(max(depth, 1), maxSearchDepth)
+}
+
+// SetMarket restricts the searches to the tracks playable in the given market,
+// an ISO 3166-1 alpha-2 country code or from_token for the current user country.
+func (c *Client) SetMarket(market string) {
+	c.market = market
+}
+
+// NewWithAuthenticator creates a new Client that obtains its token from the given Authenticator
+// and refreshes it when the API rejects it.
+func NewWithAuthenticator(httpClient doer, auth *Authenticator) *Client {
+	out := New(httpClient, "")
+	out.auth = auth
+	return out
+}
+
+func (c *Client) bearer() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return "Bearer " + c.token
+}
+
 func (c *Client) headers() map[string]string {
 	return map[string]string{
-		"Authorization": "Bearer " + c.token,
+		"Authorization": c.bearer(),
 		"Accept":        "application/json",
 		"Content-Type":  "application/json",
 	}
@@ -51,13 +100,29 @@ func (c *Client) Name() string {
 	return "spotify"
 }
 
+// ValidID tells if the ID is a track URI.
+func (c *Client) ValidID(id string) bool {
+	return strings.HasPrefix(id, "spotify:track:") && len(id) > len("spotify:track:")
+}
+
 func (c *Client) Setup(ctx context.Context) error {
+	if c.auth != nil {
+		token, err := c.auth.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", playlists.ErrUnauthorized, err)
+		}
+
+		c.mu.Lock()
+		c.token = token
+		c.mu.Unlock()
+	}
+
 	req, err := requests.New(c.baseURL + "/v1/me").Headers(c.headers()).Build(ctx)
 	if err != nil {
 		return err
 	}
 
-	res, err := send[userResponse](c.httpClient, req, http.StatusOK)
+	res, err := send[userResponse](c, req, http.StatusOK)
 	if err != nil {
 		return err
 	}
@@ -67,18 +132,45 @@ func (c *Client) Setup(ctx context.Context) error {
 	return nil
 }
 
+type trackObject struct {
+	URI     string `json:"uri"`
+	Name    string `json:"name"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Name string `json:"name"`
+	} `json:"album"`
+	DurationMS  int `json:"duration_ms"`
+	ExternalIDs struct {
+		ISRC string `json:"isrc"`
+	} `json:"external_ids"`
+	IsPlayable *bool `json:"is_playable"` // only sent when a market is given
+}
+
+func (to trackObject) track() playlists.Track {
+	artists := make([]string, 0, len(to.Artists))
+	for _, a := range to.Artists {
+		artists = append(artists, a.Name)
+	}
+
+	return playlists.Track{
+		ID:       to.URI,
+		Name:     fmt.Sprintf("%s - %s <%s>", strings.Join(artists, ", "), to.Name, to.Album.Name),
+		Title:    to.Name,
+		Artists:  artists,
+		Album:    to.Album.Name,
+		ISRC:     to.ExternalIDs.ISRC,
+		Duration: time.Duration(to.DurationMS) * time.Millisecond,
+
+		Unavailable: to.IsPlayable != nil && !*to.IsPlayable,
+	}
+}
+
 type searchResponse struct {
 	Tracks struct {
-		Items []struct {
-			URI     string `json:"uri"`
-			Name    string `json:"name"`
-			Artists []struct {
-				Name string `json:"name"`
-			} `json:"artists"`
-			Album struct {
-				Name string `json:"name"`
-			} `json:"album"`
-		} `json:"items"`
+		Items []trackObject `json:"items"`
+		Total int           `json:"total"`
 	} `json:"tracks"`
 }
 
@@ -86,63 +178,170 @@ func (sr searchResponse) tracks() []playlists.Track {
 	out := make([]playlists.Track, 0, len(sr.Tracks.Items))
 
 	for _, item := range sr.Tracks.Items {
-		artists := make([]string, 0, len(item.Artists))
-		for _, a := range item.Artists {
-			artists = append(artists, a.Name)
-		}
-
-		out = append(out, playlists.Track{
-			ID:   item.URI,
-			Name: fmt.Sprintf("%s - %s <%s>", strings.Join(artists, ", "), item.Name, item.Album.Name),
-		})
+		out = append(out, item.track())
 	}
 
 	return out
 }
 
-// SearchTracks searches for the given query and retrieves the matches.
+// SearchTracks searches for the given query and retrieves up to the search depth matches, paging through the results.
+// Blank queries, rejected by the API, find nothing.
 func (c *Client) SearchTracks(ctx context.Context, query string) ([]playlists.Track, error) {
-	u := c.baseURL + "/v1/search?type=track&q=" + url.QueryEscape(query)
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
 
-	req, err := requests.New(u).Headers(c.headers()).Build(ctx)
-	if err != nil {
-		return nil, err
+	depth := c.depth
+	if depth == 0 {
+		depth = maxSearchLimit
 	}
 
-	res, err := send[searchResponse](c.httpClient, req, http.StatusOK)
-	if err != nil {
-		return nil, err
+	var out []playlists.Track
+
+	for len(out) < depth {
+		vs := url.Values{}
+		vs.Set("type", "track")
+		vs.Set("q", query)
+		vs.Set("limit", strconv.Itoa(min(maxSearchLimit, depth-len(out))))
+		vs.Set("offset", strconv.Itoa(len(out)))
+		if c.market != "" {
+			vs.Set("market", c.market)
+		}
+
+		req, err := requests.New(c.baseURL + "/v1/search?" + vs.Encode()).Headers(c.headers()).Build(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := send[searchResponse](c, req, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+
+		page := res.tracks()
+		out = append(out, page...)
+
+		if len(page) == 0 || len(out) >= res.Tracks.Total {
+			break
+		}
+	}
+
+	return out, nil
+}
+
+// SearchISRC searches for the tracks with the given ISRC code.
+func (c *Client) SearchISRC(ctx context.Context, isrc string) ([]playlists.Track, error) {
+	if isrc == "" {
+		return nil, nil
+	}
+
+	return c.SearchTracks(ctx, "isrc:"+isrc)
+}
+
+// SearchFields searches using the track, artist and album field filters.
+// Values are double quoted as they are, dropping their own double quotes since the search has no escapes.
+func (c *Client) SearchFields(ctx context.Context, fields playlists.Fields) ([]playlists.Track, error) {
+	filters := make([]string, 0, 3)
+	for _, f := range [][2]string{{"artist", fields.Artist}, {"track", fields.Title}, {"album", fields.Album}} {
+		if v := strings.TrimSpace(strings.ReplaceAll(f[1], `"`, "")); v != "" {
+			filters = append(filters, f[0]+`:"`+v+`"`)
+		}
 	}
 
-	return res.tracks(), nil
+	return c.SearchTracks(ctx, strings.Join(filters, " "))
 }
 
 type playlistResponse struct {
 	ID string `json:"id"`
 }
 
-// CreatePlaylist creates a named playlist for the given user.
-func (c *Client) CreatePlaylist(ctx context.Context, name string) (string, error) {
+type playlistRequest struct {
+	Name          string `json:"name"`
+	Public        bool   `json:"public"`
+	Collaborative bool   `json:"collaborative,omitempty"`
+	Description   string `json:"description,omitempty"`
+}
+
+// CreatePlaylist creates a named playlist for the given user, uploading its cover image if any.
+// A failed upload returns the playlist ID along with a *playlists.CoverError.
+func (c *Client) CreatePlaylist(ctx context.Context, name string, opts playlists.PlaylistOptions) (string, error) {
 	u := c.baseURL + "/v1/users/" + c.userID + "/playlists"
-	body := strings.NewReader(fmt.Sprintf(`{"name":%q,"public":false}`, name))
 
-	req, err := requests.New(u).Method(http.MethodPost).Body(body).Headers(c.headers()).Build(ctx)
+	body, err := json.Marshal(playlistRequest{
+		Name:          name,
+		Public:        opts.Public,
+		Collaborative: opts.Collaborative,
+		Description:   opts.Description,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := requests.New(u).Method(http.MethodPost).Body(bytes.NewReader(body)).Headers(c.headers()).Build(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	res, err := send[playlistResponse](c.httpClient, req, http.StatusCreated)
+	res, err := send[playlistResponse](c, req, http.StatusCreated)
 	if err != nil {
 		return "", err
 	}
 
+	if len(opts.Cover) != 0 {
+		if err := c.uploadCover(ctx, res.ID, opts.Cover); err != nil {
+			return res.ID, &playlists.CoverError{Err: err}
+		}
+	}
+
 	return res.ID, nil
 }
 
+type imageResponse struct{}
+
+// uploadCover replaces the playlist cover with the given JPEG image, sent base64 encoded.
+func (c *Client) uploadCover(ctx context.Context, playlistID string, image []byte) error {
+	u := c.baseURL + "/v1/playlists/" + playlistID + "/images"
+	body := strings.NewReader(base64.StdEncoding.EncodeToString(image))
+
+	headers := c.headers()
+	headers["Content-Type"] = "image/jpeg"
+
+	req, err := requests.New(u).Method(http.MethodPut).Body(body).Headers(headers).Build(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = send[imageResponse](c, req, http.StatusAccepted)
+
+	return err
+}
+
 type playlistTrackResponse struct{}
 
-// PopulatePlaylist adds the given tracks to the given playlist.
-func (c *Client) PopulatePlaylist(ctx context.Context, playlistID string, tracks []string) error {
+// maxTracksPerRequest is the maximum number of items the add tracks endpoint accepts per call.
+const maxTracksPerRequest = 100
+
+// PopulatePlaylist appends the given tracks to the given playlist, in batches of up to 100 tracks.
+// The position is ignored as the Web API appends when none is sent.
+// A *playlists.PopulateError tells how many were added when a batch other than the first one fails.
+func (c *Client) PopulatePlaylist(ctx context.Context, playlistID string, _ int, tracks []string) error {
+	var batch, added int
+	for chunk := range slices.Chunk(tracks, maxTracksPerRequest) {
+		batch++
+		if err := c.addTracks(ctx, playlistID, chunk); err != nil {
+			err = fmt.Errorf("batch %d of %d: %w", batch, batches(len(tracks)), err)
+			if added == 0 {
+				return err
+			}
+			return &playlists.PopulateError{Added: added, Err: err}
+		}
+		added += len(chunk)
+	}
+
+	return nil
+}
+
+func (c *Client) addTracks(ctx context.Context, playlistID string, tracks []string) error {
 	u := c.baseURL + "/v1/playlists/" + playlistID + "/tracks"
 	body := strings.NewReader(fmt.Sprintf(`{"uris":["%s"]}`, strings.Join(tracks, `","`)))
 
@@ -151,28 +350,207 @@ func (c *Client) PopulatePlaylist(ctx context.Context, playlistID string, tracks
 		return err
 	}
 
-	_, err = send[playlistTrackResponse](c.httpClient, req, http.StatusCreated)
+	_, err = send[playlistTrackResponse](c, req, http.StatusCreated)
 
-	return err
+	return playlistError(err)
+}
+
+func batches(n int) int {
+	return (n + maxTracksPerRequest - 1) / maxTracksPerRequest
+}
+
+type playlistsResponse struct {
+	Items []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"items"`
+	Total int `json:"total"`
+}
+
+// Playlists retrieves the playlists of the current user.
+func (c *Client) Playlists(ctx context.Context) ([]playlists.Playlist, error) {
+	var out []playlists.Playlist
+
+	for {
+		u := fmt.Sprintf("%s/v1/me/playlists?limit=50&offset=%d", c.baseURL, len(out))
+
+		req, err := requests.New(u).Headers(c.headers()).Build(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := send[playlistsResponse](c, req, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			out = append(out, playlists.Playlist{ID: item.ID, Name: item.Name})
+		}
+
+		if len(res.Items) == 0 || len(out) >= res.Total {
+			return out, nil
+		}
+	}
+}
+
+type playlistItemsResponse struct {
+	Items []struct {
+		Track *trackObject `json:"track"`
+	} `json:"items"`
+	Total int `json:"total"`
+}
+
+// PlaylistTracks retrieves the tracks of the given playlist.
+func (c *Client) PlaylistTracks(ctx context.Context, playlistID string) ([]playlists.Track, error) {
+	var out []playlists.Track
+
+	for offset := 0; ; {
+		u := fmt.Sprintf("%s/v1/playlists/%s/tracks?limit=%d&offset=%d", c.baseURL, playlistID, maxTracksPerRequest, offset)
+
+		req, err := requests.New(u).Headers(c.headers()).Build(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := send[playlistItemsResponse](c, req, http.StatusOK)
+		if err != nil {
+			return nil, playlistError(err)
+		}
+
+		for _, item := range res.Items {
+			if item.Track != nil && item.Track.URI != "" {
+				out = append(out, item.Track.track())
+			}
+		}
+
+		offset += len(res.Items)
+		if len(res.Items) == 0 || offset >= res.Total {
+			return out, nil
+		}
+	}
+}
+
+// RemoveTracks removes every occurrence of the given tracks from the given playlist, in batches of up to 100 tracks.
+func (c *Client) RemoveTracks(ctx context.Context, playlistID string, tracks []string) error {
+	u := c.baseURL + "/v1/playlists/" + playlistID + "/tracks"
+
+	var batch int
+	for chunk := range slices.Chunk(tracks, maxTracksPerRequest) {
+		batch++
+
+		uris := make([]string, 0, len(chunk))
+		for _, t := range chunk {
+			uris = append(uris, fmt.Sprintf(`{"uri":%q}`, t))
+		}
+		body := strings.NewReader(fmt.Sprintf(`{"tracks":[%s]}`, strings.Join(uris, ",")))
+
+		req, err := requests.New(u).Method(http.MethodDelete).Body(body).Headers(c.headers()).Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := send[playlistTrackResponse](c, req, http.StatusOK); err != nil {
+			return fmt.Errorf("batch %d of %d: %w", batch, batches(len(tracks)), playlistError(err))
+		}
+	}
+
+	return nil
 }
 
 type response interface {
-	userResponse | searchResponse | playlistResponse | playlistTrackResponse
+	userResponse | searchResponse | playlistResponse | imageResponse | playlistTrackResponse | playlistsResponse | playlistItemsResponse
 }
 
-func send[t response](client doer, req *http.Request, expectedStatus int) (*t, error) {
-	res, err := client.Do(req)
+func send[t response](c *Client, req *http.Request, expectedStatus int) (*t, error) {
+	res, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
 	if expectedStatus != res.StatusCode {
-		return nil, parseError(res.Body)
+		if res.StatusCode == http.StatusTooManyRequests {
+			return nil, fmt.Errorf("rate limited: %s", res.Status)
+		}
+		if res.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("%w: %w", playlists.ErrUnauthorized, parseError(res.Body))
+		}
+		return nil, &statusError{status: res.StatusCode, err: parseError(res.Body)}
 	}
 
 	var out t
-	return &out, json.NewDecoder(res.Body).Decode(&out)
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// do sends the request, refreshing the token and retrying once if it was rejected as unauthorized.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	res, err := c.httpClient.Do(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || c.auth == nil {
+		return res, err
+	}
+	_ = res.Body.Close()
+
+	if err := c.refresh(req.Context(), req.Header.Get("Authorization")); err != nil {
+		return nil, fmt.Errorf("%w: refresh token: %w", playlists.ErrUnauthorized, err)
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", c.bearer())
+
+	return c.httpClient.Do(retry)
+}
+
+// refresh replaces the token unless a concurrent request already did it.
+func (c *Client) refresh(ctx context.Context, rejected string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rejected != "Bearer "+c.token {
+		return nil
+	}
+
+	token, err := c.auth.Refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.token = token
+
+	return nil
+}
+
+// statusError is returned by send for an unexpected status, leaving its meaning to the caller.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// playlistError tells a 404 status answered for a playlist ID means the playlist is missing.
+func playlistError(err error) error {
+	var serr *statusError
+	if errors.As(err, &serr) && serr.status == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", playlists.ErrPlaylistNotFound, err)
+	}
+
+	return err
 }
 
 func parseError(body io.Reader) error {
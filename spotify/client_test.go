@@ -2,11 +2,17 @@ package spotify
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/agukrapo/playlist-creator/internal/retry"
 	"github.com/agukrapo/playlist-creator/internal/tests"
+	"github.com/agukrapo/playlist-creator/playlists"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,12 +35,19 @@ func TestClient_Me(t *testing.T) {
 			name:           "error",
 			responseStatus: http.StatusUnauthorized,
 			responseBody:   tests.ReadFile(t, "test-data/me_error.json"),
-			expectedError:  "Invalid access token",
+			expectedError:  "unauthorized: Invalid access token",
+		},
+		{
+			name:           "not found",
+			responseStatus: http.StatusNotFound,
+			responseBody:   `{"error":{"status":404,"message":"Service not found"}}`,
+			expectedError:  "Service not found",
 		},
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("GET /v1/me", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodGet, req.Method)
 				assert.Equal(t, "/v1/me", req.URL.Path)
 				assert.Equal(t, "Bearer oauth-token", req.Header.Get("Authorization"))
@@ -45,17 +58,18 @@ func TestClient_Me(t *testing.T) {
 				w.WriteHeader(test.responseStatus)
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
 
 			client := &Client{
-				baseURL:    svr.URL,
+				baseURL:    api.URL,
 				token:      "oauth-token",
 				httpClient: http.DefaultClient,
 			}
 
 			err := client.Setup(context.Background())
 			require.Equal(t, test.expectedError, tests.AsString(err))
+			assert.Equal(t, test.responseStatus == http.StatusUnauthorized, errors.Is(err, playlists.ErrUnauthorized))
+			assert.False(t, errors.Is(err, playlists.ErrPlaylistNotFound), "only the playlist calls report missing playlists")
 
 			assert.Equal(t, test.expected, client.userID)
 		})
@@ -87,10 +101,11 @@ func TestClient_SearchTrack(t *testing.T) {
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("GET /v1/search", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodGet, req.Method)
 				assert.Equal(t, "/v1/search", req.URL.Path)
-				assert.Equal(t, "type=track&q=query", req.URL.RawQuery)
+				assert.Equal(t, "limit=50&offset=0&q=query&type=track", req.URL.RawQuery)
 				assert.Equal(t, "Bearer oauth-token", req.Header.Get("Authorization"))
 				assert.Equal(t, "application/json", req.Header.Get("Accept"))
 				assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
@@ -99,11 +114,10 @@ func TestClient_SearchTrack(t *testing.T) {
 				w.WriteHeader(test.responseStatus)
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
 
 			client := &Client{
-				baseURL:    svr.URL,
+				baseURL:    api.URL,
 				token:      "oauth-token",
 				httpClient: http.DefaultClient,
 			}
@@ -124,6 +138,137 @@ func TestClient_SearchTrack(t *testing.T) {
 	}
 }
 
+func TestClient_SearchTracks_pages(t *testing.T) {
+	table := []struct {
+		name            string
+		total           int
+		depth           int
+		expectedPages   []string
+		expectedMatches int
+	}{
+		{
+			name:            "depth reached",
+			total:           300,
+			depth:           110,
+			expectedPages:   []string{"50/0", "50/50", "10/100"},
+			expectedMatches: 110,
+		},
+		{
+			name:            "results run out",
+			total:           60,
+			depth:           200,
+			expectedPages:   []string{"50/0", "50/50"},
+			expectedMatches: 60,
+		},
+		{
+			name:            "single page",
+			total:           300,
+			expectedPages:   []string{"50/0"},
+			expectedMatches: 50,
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			var pages []string
+			api := newFakeAPI(t)
+			api.handle("GET /v1/search", func(w http.ResponseWriter, req *http.Request) {
+				vs := req.URL.Query()
+				assert.Equal(t, "query", vs.Get("q"))
+				assert.Equal(t, "from_token", vs.Get("market"))
+
+				limit, err := strconv.Atoi(vs.Get("limit"))
+				require.NoError(t, err)
+				offset, err := strconv.Atoi(vs.Get("offset"))
+				require.NoError(t, err)
+				pages = append(pages, fmt.Sprintf("%d/%d", limit, offset))
+
+				var res searchResponse
+				res.Tracks.Total = test.total
+				for i := offset; i < min(offset+limit, test.total); i++ {
+					res.Tracks.Items = append(res.Tracks.Items, trackObject{URI: fmt.Sprintf("spotify:track:%d", i)})
+				}
+				assert.NoError(t, json.NewEncoder(w).Encode(res))
+			})
+
+			client := New(http.DefaultClient, "oauth-token")
+			client.baseURL = api.URL
+			client.SetMarket("from_token")
+			if test.depth != 0 {
+				client.SetSearchDepth(test.depth)
+			}
+
+			matches, err := client.SearchTracks(context.Background(), "query")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedPages, pages)
+			require.Len(t, matches, test.expectedMatches)
+			assert.Equal(t, "spotify:track:0", matches[0].ID)
+			assert.Equal(t, fmt.Sprintf("spotify:track:%d", test.expectedMatches-1), matches[len(matches)-1].ID)
+		})
+	}
+}
+
+func TestClient_SearchTracks_playable(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("GET /v1/search", func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(`{"tracks":{"total":3,"items":[
+			{"uri":"spotify:track:playable","is_playable":true},
+			{"uri":"spotify:track:unplayable","is_playable":false},
+			{"uri":"spotify:track:unknown"}
+		]}}`))
+		assert.NoError(t, err)
+	})
+
+	client := New(http.DefaultClient, "oauth-token")
+	client.baseURL = api.URL
+	client.SetMarket("AR")
+
+	matches, err := client.SearchTracks(context.Background(), "query")
+	require.NoError(t, err)
+
+	require.Len(t, matches, 3)
+	assert.False(t, matches[0].Unavailable)
+	assert.True(t, matches[1].Unavailable)
+	assert.False(t, matches[2].Unavailable, "is_playable is only sent with a market")
+}
+
+func TestClient_SearchTrack_throttled(t *testing.T) {
+	table := []struct {
+		name          string
+		throttled     int
+		expectedError string
+	}{
+		{
+			name:      "retried",
+			throttled: 2,
+		},
+		{
+			name:          "attempts run out",
+			throttled:     3,
+			expectedError: "rate limited: 429 Too Many Requests",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.throttled = test.throttled
+
+			client := &Client{
+				baseURL:    api.URL,
+				token:      "oauth-token",
+				httpClient: retry.New(http.DefaultClient, retry.Attempts(3)),
+			}
+
+			matches, err := client.SearchTracks(context.Background(), "tahitian moon")
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			if test.expectedError == "" {
+				assert.Len(t, matches, 1)
+			}
+		})
+	}
+}
+
 func TestClient_CreatePlaylist(t *testing.T) {
 	table := []struct {
 		name           string
@@ -149,7 +294,8 @@ func TestClient_CreatePlaylist(t *testing.T) {
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("POST /v1/users/userID/playlists", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodPost, req.Method)
 				assert.Equal(t, "/v1/users/userID/playlists", req.URL.Path)
 				assert.Equal(t, "Bearer oauth-token", req.Header.Get("Authorization"))
@@ -160,17 +306,16 @@ func TestClient_CreatePlaylist(t *testing.T) {
 				w.WriteHeader(test.responseStatus)
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
 
 			client := &Client{
-				baseURL:    svr.URL,
+				baseURL:    api.URL,
 				token:      "oauth-token",
 				httpClient: http.DefaultClient,
 				userID:     "userID",
 			}
 
-			id, err := client.CreatePlaylist(context.Background(), "playlistName")
+			id, err := client.CreatePlaylist(context.Background(), "playlistName", playlists.PlaylistOptions{})
 			require.Equal(t, test.expectedError, tests.AsString(err))
 
 			assert.Equal(t, test.expectedID, id)
@@ -178,6 +323,68 @@ func TestClient_CreatePlaylist(t *testing.T) {
 	}
 }
 
+func TestClient_CreatePlaylist_options(t *testing.T) {
+	cover := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+
+	var uploaded bool
+	api := newFakeAPI(t)
+	api.handle("POST /v1/users/userID/playlists", func(w http.ResponseWriter, req *http.Request) {
+		assert.JSONEq(t, `{"name":"playlistName","public":false,"collaborative":true,"description":"Friday & party"}`, tests.ReadBody(t, req))
+
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/create_playlist_ok.json")))
+		assert.NoError(t, err)
+	})
+	api.handle("PUT /v1/playlists/ujEWyhJniu4K7Kamfiki/images", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "image/jpeg", req.Header.Get("Content-Type"))
+		assert.Equal(t, "/9j/4A==", tests.ReadBody(t, req))
+
+		uploaded = true
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	client := &Client{
+		baseURL:    api.URL,
+		token:      "oauth-token",
+		httpClient: http.DefaultClient,
+		userID:     "userID",
+	}
+
+	id, err := client.CreatePlaylist(context.Background(), "playlistName", playlists.PlaylistOptions{
+		Collaborative: true,
+		Description:   "Friday & party",
+		Cover:         cover,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ujEWyhJniu4K7Kamfiki", id)
+	assert.True(t, uploaded)
+}
+
+func TestClient_CreatePlaylist_coverError(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("PUT /v1/playlists/P1/images", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_, err := w.Write([]byte(`{"error":{"status":413,"message":"Payload too large"}}`))
+		assert.NoError(t, err)
+	})
+
+	client := New(http.DefaultClient, "oauth-token")
+	client.baseURL = api.URL
+	require.NoError(t, client.Setup(context.Background()))
+
+	id, err := client.CreatePlaylist(context.Background(), "playlistName", playlists.PlaylistOptions{Cover: []byte{0xFF, 0xD8, 0xFF}})
+	require.EqualError(t, err, "cover not uploaded: Payload too large")
+
+	var cerr *playlists.CoverError
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, "P1", id)
+
+	list, err := client.Playlists(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []playlists.Playlist{{ID: "P1", Name: "playlistName"}}, list, "the playlist was created")
+}
+
 func TestClient_AddTracksToPlaylist(t *testing.T) {
 	table := []struct {
 		name           string
@@ -194,12 +401,13 @@ func TestClient_AddTracksToPlaylist(t *testing.T) {
 			name:           "error",
 			responseStatus: http.StatusNotFound,
 			responseBody:   tests.ReadFile(t, "test-data/add_tracks_to_playlist_error.json"),
-			expectedError:  "Invalid playlist Id",
+			expectedError:  "batch 1 of 1: playlist not found: Invalid playlist Id",
 		},
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			api := newFakeAPI(t)
+			api.handle("POST /v1/playlists/playlistID/tracks", func(w http.ResponseWriter, req *http.Request) {
 				assert.Equal(t, http.MethodPost, req.Method)
 				assert.Equal(t, "/v1/playlists/playlistID/tracks", req.URL.Path)
 				assert.Equal(t, "Bearer oauth-token", req.Header.Get("Authorization"))
@@ -210,17 +418,319 @@ func TestClient_AddTracksToPlaylist(t *testing.T) {
 				w.WriteHeader(test.responseStatus)
 				_, err := w.Write([]byte(test.responseBody))
 				assert.NoError(t, err)
-			}))
-			defer svr.Close()
+			})
 
 			client := &Client{
-				baseURL:    svr.URL,
+				baseURL:    api.URL,
 				token:      "oauth-token",
 				httpClient: http.DefaultClient,
 			}
 
-			err := client.PopulatePlaylist(context.Background(), "playlistID", []string{"trackID"})
+			err := client.PopulatePlaylist(context.Background(), "playlistID", 0, []string{"trackID"})
 			require.Equal(t, test.expectedError, tests.AsString(err))
 		})
 	}
 }
+
+func TestClient_AddTracksToPlaylist_batches(t *testing.T) {
+	tracks := make([]string, 250)
+	for i := range tracks {
+		tracks[i] = fmt.Sprintf("track%03d", i)
+	}
+
+	table := []struct {
+		name            string
+		failingBatch    int
+		expectedBatches int
+		expectedError   string
+	}{
+		{
+			name:            "ok",
+			expectedBatches: 3,
+		},
+		{
+			name:            "error",
+			failingBatch:    2,
+			expectedBatches: 2,
+			expectedError:   "100 tracks already added: batch 2 of 3: playlist not found: Invalid playlist Id",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			var received [][]string
+
+			api := newFakeAPI(t)
+			api.handle("POST /v1/playlists/playlistID/tracks", func(w http.ResponseWriter, req *http.Request) {
+				var body struct {
+					URIs []string `json:"uris"`
+				}
+				assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+				received = append(received, body.URIs)
+
+				status, file := http.StatusCreated, "test-data/add_tracks_to_playlist_ok.json"
+				if len(received) == test.failingBatch {
+					status, file = http.StatusNotFound, "test-data/add_tracks_to_playlist_error.json"
+				}
+
+				w.WriteHeader(status)
+				_, err := w.Write([]byte(tests.ReadFile(t, file)))
+				assert.NoError(t, err)
+			})
+
+			client := &Client{
+				baseURL:    api.URL,
+				token:      "oauth-token",
+				httpClient: http.DefaultClient,
+			}
+
+			err := client.PopulatePlaylist(context.Background(), "playlistID", 0, tracks)
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			if err != nil {
+				var perr *playlists.PopulateError
+				require.ErrorAs(t, err, &perr)
+				assert.Equal(t, 100, perr.Added)
+			}
+
+			require.Len(t, received, test.expectedBatches)
+			assert.Equal(t, tracks[:100], received[0])
+			assert.Equal(t, tracks[100:200], received[1])
+			if test.expectedBatches == 3 {
+				assert.Equal(t, tracks[200:], received[2])
+			}
+		})
+	}
+}
+
+type fakeAuthenticator struct {
+	token, refreshed string
+	refreshes        int
+}
+
+func (fa *fakeAuthenticator) Token(context.Context) (string, error) {
+	return fa.token, nil
+}
+
+func (fa *fakeAuthenticator) Refresh(context.Context) (string, error) {
+	fa.refreshes++
+	return fa.refreshed, nil
+}
+
+func TestClient_refreshOnUnauthorized(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("POST /v1/users/userID/playlists", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, `{"name":"playlistName","public":false}`, tests.ReadBody(t, req))
+
+		if req.Header.Get("Authorization") != "Bearer fresh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, err := w.Write([]byte(tests.ReadFile(t, "test-data/me_error.json")))
+			assert.NoError(t, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/create_playlist_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	auth := &fakeAuthenticator{token: "expired-token", refreshed: "fresh-token"}
+	client := &Client{
+		baseURL:    api.URL,
+		token:      "expired-token",
+		httpClient: http.DefaultClient,
+		userID:     "userID",
+		auth:       auth,
+	}
+
+	id, err := client.CreatePlaylist(context.Background(), "playlistName", playlists.PlaylistOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ujEWyhJniu4K7Kamfiki", id)
+	assert.Equal(t, 1, auth.refreshes)
+	assert.Equal(t, "fresh-token", client.token)
+}
+
+func TestClient_Playlists(t *testing.T) {
+	table := []struct {
+		name           string
+		responseStatus int
+		responseBody   string
+		expected       []playlists.Playlist
+		expectedError  string
+	}{
+		{
+			name:           "ok",
+			responseStatus: http.StatusOK,
+			responseBody:   tests.ReadFile(t, "test-data/playlists_ok.json"),
+			expected:       []playlists.Playlist{{ID: "3cEYpjA9oz9GiPac4AsH4n", Name: "Friday party"}},
+		},
+		{
+			name:           "error",
+			responseStatus: http.StatusUnauthorized,
+			responseBody:   tests.ReadFile(t, "test-data/me_error.json"),
+			expectedError:  "unauthorized: Invalid access token",
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.handle("GET /v1/me/playlists", func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, http.MethodGet, req.Method)
+				assert.Equal(t, "/v1/me/playlists", req.URL.Path)
+				assert.Equal(t, "limit=50&offset=0", req.URL.RawQuery)
+				assert.Equal(t, "Bearer oauth-token", req.Header.Get("Authorization"))
+
+				w.WriteHeader(test.responseStatus)
+				_, err := w.Write([]byte(test.responseBody))
+				assert.NoError(t, err)
+			})
+
+			client := &Client{
+				baseURL:    api.URL,
+				token:      "oauth-token",
+				httpClient: http.DefaultClient,
+			}
+
+			actual, err := client.Playlists(context.Background())
+			require.Equal(t, test.expectedError, tests.AsString(err))
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestClient_PlaylistTracks(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("GET /v1/playlists/playlistID/tracks", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, "/v1/playlists/playlistID/tracks", req.URL.Path)
+		assert.Equal(t, "limit=100&offset=0", req.URL.RawQuery)
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/playlist_tracks_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := &Client{
+		baseURL:    api.URL,
+		token:      "oauth-token",
+		httpClient: http.DefaultClient,
+	}
+
+	actual, err := client.PlaylistTracks(context.Background(), "playlistID")
+	require.NoError(t, err)
+
+	assert.Equal(t, []playlists.Track{{
+		ID:       "spotify:track:58W2OncAqstyVAumWdwTOz",
+		Name:     "The Clash - Mustapha Dance <Super Black Market Clash>",
+		Title:    "Mustapha Dance",
+		Artists:  []string{"The Clash"},
+		Album:    "Super Black Market Clash",
+		ISRC:     "GBBBN0009372",
+		Duration: 265733 * time.Millisecond,
+	}}, actual)
+}
+
+func TestClient_RemoveTracks(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("DELETE /v1/playlists/playlistID/tracks", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, "/v1/playlists/playlistID/tracks", req.URL.Path)
+		assert.JSONEq(t, `{"tracks":[{"uri":"trackA"},{"uri":"trackB"}]}`, tests.ReadBody(t, req))
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/remove_tracks_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := &Client{
+		baseURL:    api.URL,
+		token:      "oauth-token",
+		httpClient: http.DefaultClient,
+	}
+
+	err := client.RemoveTracks(context.Background(), "playlistID", []string{"trackA", "trackB"})
+	require.NoError(t, err)
+}
+
+func TestClient_SearchISRC(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("GET /v1/search", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, "/v1/search", req.URL.Path)
+		assert.Equal(t, "limit=50&offset=0&q=isrc%3AGBBBN0009372&type=track", req.URL.RawQuery)
+
+		_, err := w.Write([]byte(tests.ReadFile(t, "test-data/search_track_ok.json")))
+		assert.NoError(t, err)
+	})
+
+	client := &Client{
+		baseURL:    api.URL,
+		token:      "oauth-token",
+		httpClient: http.DefaultClient,
+	}
+
+	matches, err := client.SearchISRC(context.Background(), "GBBBN0009372")
+	require.NoError(t, err)
+
+	require.Len(t, matches, 1)
+	assert.Equal(t, "spotify:track:58W2OncAqstyVAumWdwTOz", matches[0].ID)
+	assert.Equal(t, "GBBBN0009372", matches[0].ISRC)
+	assert.Equal(t, "Super Black Market Clash", matches[0].Album)
+	assert.Equal(t, 265733*time.Millisecond, matches[0].Duration)
+}
+
+func TestClient_SearchFields(t *testing.T) {
+	table := []struct {
+		name          string
+		fields        playlists.Fields
+		expectedQuery string
+	}{
+		{
+			name:          "artist and title",
+			fields:        playlists.Fields{Artist: "The Clash", Title: "Mustapha Dance"},
+			expectedQuery: `artist:"The Clash" track:"Mustapha Dance"`,
+		},
+		{
+			name:          "quotes and accents",
+			fields:        playlists.Fields{Artist: "Sigur Rós", Title: `Hoppípolla "Live"`, Album: "Takk..."},
+			expectedQuery: `artist:"Sigur Rós" track:"Hoppípolla Live" album:"Takk..."`,
+		},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			api := newFakeAPI(t)
+			api.handle("GET /v1/search", func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/v1/search", req.URL.Path)
+				assert.Equal(t, "track", req.URL.Query().Get("type"))
+				assert.Equal(t, test.expectedQuery, req.URL.Query().Get("q"))
+
+				_, err := w.Write([]byte(tests.ReadFile(t, "test-data/search_track_ok.json")))
+				assert.NoError(t, err)
+			})
+
+			client := &Client{
+				baseURL:    api.URL,
+				token:      "oauth-token",
+				httpClient: http.DefaultClient,
+			}
+
+			matches, err := client.SearchFields(context.Background(), test.fields)
+			require.NoError(t, err)
+
+			assert.Len(t, matches, 1)
+		})
+	}
+}
+
+func TestClient_ValidID(t *testing.T) {
+	table := map[string]bool{
+		"spotify:track:4u7EnebtmKWzUH433cf5Qv": true,
+		"spotify:track:":                       false,
+		"spotify:album:4u7EnebtmKWzUH433cf5Qv": false,
+		"3135556":                              false,
+	}
+	for id, expected := range table {
+		t.Run(id, func(t *testing.T) {
+			assert.Equal(t, expected, New(nil, "").ValidID(id))
+		})
+	}
+}
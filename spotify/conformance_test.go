@@ -0,0 +1,34 @@
+package spotify
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/agukrapo/playlist-creator/internal/retry"
+	"github.com/agukrapo/playlist-creator/playlists"
+	"github.com/agukrapo/playlist-creator/playlists/playliststest"
+)
+
+func TestClient_conformance(t *testing.T) {
+	client := func(token string, configure func(api *fakeAPI)) func(t *testing.T) playlists.Target {
+		return func(t *testing.T) playlists.Target {
+			api := newFakeAPI(t)
+			configure(api)
+
+			out := New(retry.New(http.DefaultClient, retry.Attempts(3)), token)
+			out.baseURL = api.URL
+			return out
+		}
+	}
+
+	playliststest.Suite{
+		New:          client("oauth-token", func(*fakeAPI) {}),
+		Unauthorized: client("expired-token", func(*fakeAPI) {}),
+		Throttled:    client("oauth-token", func(api *fakeAPI) { api.throttled = 2 }),
+		Failing:      client("oauth-token", func(api *fakeAPI) { api.maxAdds = 1 }),
+		Query:        "porno for pyros",
+		ISRC:         "USWB19500351",
+		Fields:       playlists.Fields{Artist: "Queen", Title: "Bohemian Rhapsody"},
+		Tracks:       []string{"spotify:track:1", "spotify:track:2", "spotify:track:3"},
+	}.Run(t)
+}
@@ -0,0 +1,233 @@
+package spotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI serves the subset of the Web API used by the Client from an in-memory catalog.
+// Tests replace single routes with handle to check the requests and reply with recorded responses.
+type fakeAPI struct {
+	*httptest.Server
+
+	routes *http.ServeMux // routes replaced by the test, served first
+	tracks []trackObject
+
+	mu        sync.Mutex
+	playlists map[string]*fakePlaylist
+	order     []string
+	throttled int // requests to answer as rate limited
+	maxAdds   int // add tracks requests accepted before failing the rest, zero for no limit
+	adds      int
+}
+
+type fakePlaylist struct {
+	name   string
+	tracks []string
+}
+
+// fakeCatalog holds the tracks served by fakeAPI.
+const fakeCatalog = `[
+	{"uri":"spotify:track:1","name":"Tahitian Moon","artists":[{"name":"Porno For Pyros"}],"album":{"name":"Good God's Urge"},"external_ids":{"isrc":"USWB19500351"}},
+	{"uri":"spotify:track:2","name":"Pets","artists":[{"name":"Porno For Pyros"}],"album":{"name":"Porno For Pyros"},"external_ids":{"isrc":"USWB19300312"}},
+	{"uri":"spotify:track:3","name":"Bohemian Rhapsody","artists":[{"name":"Queen"}],"album":{"name":"A Night at the Opera"},"external_ids":{"isrc":"GBUM71029604"}}
+]`
+
+// newFakeAPI starts a fakeAPI accepting the "oauth-token" token, closed when the test ends.
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{routes: http.NewServeMux(), playlists: make(map[string]*fakePlaylist)}
+	require.NoError(t, json.Unmarshal([]byte(fakeCatalog), &api.tracks))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/me", api.me)
+	mux.HandleFunc("GET /v1/search", api.search)
+	mux.HandleFunc("POST /v1/users/{user}/playlists", api.createPlaylist)
+	mux.HandleFunc("GET /v1/me/playlists", api.listPlaylists)
+	mux.HandleFunc("GET /v1/playlists/{id}/tracks", api.playlistTracks)
+	mux.HandleFunc("POST /v1/playlists/{id}/tracks", api.addTracks)
+	mux.HandleFunc("DELETE /v1/playlists/{id}/tracks", api.removeTracks)
+
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if h, pattern := api.routes.Handler(req); pattern != "" {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		if api.throttle() {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		if req.Header.Get("Authorization") != "Bearer oauth-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"status":401,"message":"Invalid access token"}}`))
+			return
+		}
+
+		mux.ServeHTTP(w, req)
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// handle replaces the route matching the pattern.
+func (api *fakeAPI) handle(pattern string, handler http.HandlerFunc) {
+	api.routes.HandleFunc(pattern, handler)
+}
+
+func (api *fakeAPI) throttle() bool {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if api.throttled == 0 {
+		return false
+	}
+
+	api.throttled--
+	return true
+}
+
+func (api *fakeAPI) me(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte(`{"id":"user"}`))
+}
+
+// search matches the tracks containing every term of the query, field filters and ISRCs included.
+func (api *fakeAPI) search(w http.ResponseWriter, req *http.Request) {
+	q := strings.ToLower(req.URL.Query().Get("q"))
+	if strings.TrimSpace(q) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"status":400,"message":"No search query"}}`))
+		return
+	}
+
+	var res searchResponse
+	for _, to := range api.tracks {
+		text := strings.ToLower(fmt.Sprintf("%s %s %s isrc:%s", to.Artists[0].Name, to.Name, to.Album.Name, to.ExternalIDs.ISRC))
+
+		match := true
+		for _, term := range strings.Fields(strings.NewReplacer(`"`, "", "artist:", "", "track:", "", "album:", "").Replace(q)) {
+			match = match && strings.Contains(text, term)
+		}
+		if match {
+			res.Tracks.Items = append(res.Tracks.Items, to)
+		}
+	}
+	res.Tracks.Total = len(res.Tracks.Items)
+
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (api *fakeAPI) createPlaylist(w http.ResponseWriter, req *http.Request) {
+	var in playlistRequest
+	if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	id := fmt.Sprintf("P%d", len(api.order)+1)
+	api.playlists[id] = &fakePlaylist{name: in.Name}
+	api.order = append(api.order, id)
+
+	w.WriteHeader(http.StatusCreated)
+	_, _ = fmt.Fprintf(w, `{"id":%q}`, id)
+}
+
+func (api *fakeAPI) listPlaylists(w http.ResponseWriter, _ *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	items := make([]map[string]string, 0, len(api.order))
+	for _, id := range api.order {
+		items = append(items, map[string]string{"id": id, "name": api.playlists[id].name})
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"items": items, "total": len(items)})
+}
+
+// playlist runs fn with the playlist of the request path, replying 404 when unknown.
+func (api *fakeAPI) playlist(w http.ResponseWriter, req *http.Request, fn func(p *fakePlaylist)) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	p, ok := api.playlists[req.PathValue("id")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"status":404,"message":"Not found."}}`))
+		return
+	}
+
+	fn(p)
+}
+
+func (api *fakeAPI) playlistTracks(w http.ResponseWriter, req *http.Request) {
+	api.playlist(w, req, func(p *fakePlaylist) {
+		items := make([]map[string]any, 0, len(p.tracks))
+		for _, uri := range p.tracks {
+			i := slices.IndexFunc(api.tracks, func(to trackObject) bool { return to.URI == uri })
+			items = append(items, map[string]any{"track": api.tracks[i]})
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": items, "total": len(items)})
+	})
+}
+
+func (api *fakeAPI) addTracks(w http.ResponseWriter, req *http.Request) {
+	api.playlist(w, req, func(p *fakePlaylist) {
+		var in struct {
+			URIs []string `json:"uris"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if api.adds++; api.maxAdds != 0 && api.adds > api.maxAdds {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"status":400,"message":"Payload contains a non-existing ID"}}`))
+			return
+		}
+
+		p.tracks = append(p.tracks, in.URIs...)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"snapshot_id":"_SNAPSHOT"}`))
+	})
+}
+
+func (api *fakeAPI) removeTracks(w http.ResponseWriter, req *http.Request) {
+	api.playlist(w, req, func(p *fakePlaylist) {
+		var in struct {
+			Tracks []struct {
+				URI string `json:"uri"`
+			} `json:"tracks"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		removed := make([]string, 0, len(in.Tracks))
+		for _, t := range in.Tracks {
+			removed = append(removed, t.URI)
+		}
+
+		p.tracks = slices.DeleteFunc(p.tracks, func(uri string) bool {
+			return slices.Contains(removed, uri)
+		})
+
+		_, _ = w.Write([]byte(`{"snapshot_id":"_SNAPSHOT"}`))
+	})
+}